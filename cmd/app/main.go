@@ -1,26 +1,15 @@
 package main
 
 import (
-	"log"
+	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
-	"github.com/asliddinberdiev/i_tv_task/internal/app"
+	"github.com/asliddinberdiev/i_tv_task/internal/cli"
 )
 
 func main() {
-	app := app.NewCreateApp()
-
-	if err := app.Start(); err != nil {
-		log.Fatal(err)
-	}
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	if err := app.Stop(); err != nil {
-		log.Fatal(err)
+	if err := cli.NewRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
 }
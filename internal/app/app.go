@@ -6,13 +6,25 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/asliddinberdiev/i_tv_task/internal/blobstore"
 	"github.com/asliddinberdiev/i_tv_task/internal/config"
 	deliveryHttp "github.com/asliddinberdiev/i_tv_task/internal/delivery/http"
 	v1 "github.com/asliddinberdiev/i_tv_task/internal/delivery/http/v1"
+	"github.com/asliddinberdiev/i_tv_task/internal/mailer"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/artwork"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/credit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/genre"
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/movie"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/rating"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/review"
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/user"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/watchlist"
 	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
 	logger "github.com/asliddinberdiev/i_tv_task/pkgs/logger/zap"
+	"github.com/pkg/errors"
+	"go.uber.org/dig"
 	"go.uber.org/fx"
 )
 
@@ -31,28 +43,19 @@ func NewCreateApp() *App {
 	defer logger.Cleanup(log)
 
 	app := fx.New(
-		fx.Provide(func() *config.Config {
-			return cfg
-		}),
-
-		fx.Provide(func() logger.Logger {
-			return log
-		}),
-
-		postgres.Module,
-		user.Module,
-		movie.Module,
+		core(cfg, log),
 		deliveryHttp.Module,
 		v1.Module,
 
-		fx.Invoke(func(lc fx.Lifecycle, handler *deliveryHttp.Handler, cfg *config.Config, log logger.Logger, psql postgres.PostgresDB) {
+		fx.Invoke(func(lc fx.Lifecycle, handler *deliveryHttp.Handler, cfg *config.Config, log logger.Logger, psql postgres.PostgresDB, users user.Service) {
 			lc.Append(fx.Hook{
 				OnStart: func(ctx context.Context) error {
-					if err := psql.AutoMigrate(
-						&user.User{},
-						&movie.Movie{},
-					); err != nil {
-						log.Error("failed to auto migrate", logger.Error(err))
+					if err := migrateSchema(ctx, cfg, log, psql); err != nil {
+						return err
+					}
+
+					if err := users.BootstrapAdmin(); err != nil {
+						log.Error("failed to bootstrap admin", logger.Error(err))
 						return err
 					}
 
@@ -89,6 +92,56 @@ func NewCreateApp() *App {
 	}
 }
 
+// core provides the config, the logger and every module but the HTTP
+// delivery, which is all the commands besides serve need.
+func core(cfg *config.Config, log logger.Logger) fx.Option {
+	return fx.Options(
+		fx.Provide(func() *config.Config {
+			return cfg
+		}),
+
+		fx.Provide(func() logger.Logger {
+			return log
+		}),
+
+		postgres.Module,
+		mailer.Module,
+		blobstore.Module,
+		audit.Module,
+		user.Module,
+		genre.Module,
+		person.Module,
+		credit.Module,
+		rating.Module,
+		review.Module,
+		watchlist.Module,
+		artwork.Module,
+		movie.Module,
+	)
+}
+
+// Populate builds the app without the HTTP server and fills targets with
+// values from it, like fx.Populate. The app is never started, so none of its
+// lifecycle hooks run.
+func Populate(targets ...interface{}) error {
+	cfg, err := config.NewConfig()
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	log := logger.NewLogger(cfg.App.LogLevel, cfg.App.ServiceName)
+	defer logger.Cleanup(log)
+
+	app := fx.New(
+		core(cfg, log),
+		fx.NopLogger,
+		fx.Populate(targets...),
+	)
+	// The error of a constructor deep down comes wrapped in every dependency
+	// on the way; only the constructor's own error tells what went wrong.
+	return dig.RootCause(app.Err())
+}
+
 func (a *App) Start() error {
 	return a.fxApp.Start(context.Background())
 }
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/artwork"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/credit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/genre"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/movie"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/rating"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/review"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/user"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/watchlist"
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	logger "github.com/asliddinberdiev/i_tv_task/pkgs/logger/zap"
+)
+
+// migrateSchema runs on start. With cfg.Postgres.AutoMigrate in the dev
+// environment the schema is synced with the models; otherwise migrations are
+// left to `app migrate` and pending ones are only reported.
+func migrateSchema(ctx context.Context, cfg *config.Config, log logger.Logger, psql postgres.PostgresDB) error {
+	if cfg.App.Environment == "dev" && cfg.Postgres.AutoMigrate {
+		if err := psql.AutoMigrate(
+			&user.User{},
+			&user.RefreshToken{},
+			&user.RevokedToken{},
+			&user.TokenCutoff{},
+			&user.LoginAttempt{},
+			&user.OneTimeCode{},
+			&user.PasswordResetToken{},
+			&genre.Genre{},
+			&person.Person{},
+			&movie.Movie{},
+			&credit.Credit{},
+			&rating.Rating{},
+			&rating.Stats{},
+			&review.Review{},
+			&review.Report{},
+			&watchlist.Item{},
+			&watchlist.Event{},
+			&artwork.Image{},
+			&audit.Entry{},
+		); err != nil {
+			log.Error("failed to auto migrate", logger.Error(err))
+			return err
+		}
+		return nil
+	}
+
+	migrator, err := postgres.NewMigrator(psql)
+	if err != nil {
+		return err
+	}
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		log.Error("failed to read migration status", logger.Error(err))
+		return err
+	}
+	if status.Dirty || status.Version != migrator.Latest() {
+		log.Warn("database schema is not up to date, run app migrate up",
+			logger.Any("version", status.Version),
+			logger.Any("latest", migrator.Latest()),
+			logger.Bool("dirty", status.Dirty),
+		)
+	}
+	return nil
+}
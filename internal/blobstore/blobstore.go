@@ -0,0 +1,33 @@
+package blobstore
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/blob"
+	"github.com/pkg/errors"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Module("blobstore", fx.Provide(NewStore))
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+func NewStore(cfg *config.Config) (blob.Store, error) {
+	switch cfg.Storage.Driver {
+	case DriverLocal, "":
+		return blob.NewLocalStore(cfg.Storage.Dir)
+	case DriverS3:
+		return blob.NewS3Store(blob.S3Config{
+			Endpoint:  cfg.Storage.S3Endpoint,
+			Region:    cfg.Storage.S3Region,
+			Bucket:    cfg.Storage.S3Bucket,
+			AccessKey: cfg.Storage.S3AccessKey,
+			SecretKey: cfg.Storage.S3SecretKey,
+			PathStyle: cfg.Storage.S3PathStyle,
+		})
+	default:
+		return nil, errors.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/app"
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/migrate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply or revert database migrations",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply every pending migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(func(migrator *migrate.Migrator) error {
+					done, err := migrator.Up(cmd.Context())
+					return report(cmd.OutOrStdout(), "applied", done, err)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "down [N]",
+			Short: "Revert the last N migrations, one by default",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				steps := 1
+				if len(args) == 1 {
+					var err error
+					if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+						return errors.Errorf("invalid number of steps %q", args[0])
+					}
+				}
+				return withMigrator(func(migrator *migrate.Migrator) error {
+					done, err := migrator.Down(cmd.Context(), steps)
+					return report(cmd.OutOrStdout(), "reverted", done, err)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "goto N",
+			Short: "Migrate up or down to version N; 0 reverts everything",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				version, err := strconv.ParseUint(args[0], 10, 64)
+				if err != nil {
+					return errors.Errorf("invalid version %q", args[0])
+				}
+				return withMigrator(func(migrator *migrate.Migrator) error {
+					done, err := migrator.Goto(cmd.Context(), version)
+					return report(cmd.OutOrStdout(), "migrated", done, err)
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "List the migrations and the version of the database",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(func(migrator *migrate.Migrator) error {
+					status, err := migrator.Status(cmd.Context())
+					if err != nil {
+						return err
+					}
+					printStatus(cmd.OutOrStdout(), status)
+					return nil
+				})
+			},
+		},
+	)
+
+	return cmd
+}
+
+// withMigrator connects to the database, runs fn and disconnects. Commands
+// check their arguments first, so usage errors never wait on the database.
+func withMigrator(fn func(migrator *migrate.Migrator) error) error {
+	var psql postgres.PostgresDB
+	if err := app.Populate(&psql); err != nil {
+		return err
+	}
+	defer psql.Close()
+
+	migrator, err := postgres.NewMigrator(psql)
+	if err != nil {
+		return err
+	}
+	return fn(migrator)
+}
+
+// report prints the migrations that ran, which are kept even when a later
+// one failed, and passes err on.
+func report(w io.Writer, verb string, done []migrate.Migration, err error) error {
+	for _, m := range done {
+		fmt.Fprintf(w, "%s %06d_%s\n", verb, m.Version, m.Name)
+	}
+	if len(done) == 0 && err == nil {
+		fmt.Fprintln(w, "no change")
+	}
+	return err
+}
+
+func printStatus(w io.Writer, status *migrate.Status) {
+	for _, m := range status.Migrations {
+		state := "pending"
+		if m.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%-8s %06d_%s\n", state, m.Version, m.Name)
+	}
+
+	dirty := ""
+	if status.Dirty {
+		dirty = " (dirty)"
+	}
+	fmt.Fprintf(w, "version %d%s\n", status.Version, dirty)
+}
@@ -0,0 +1,26 @@
+// Package cli holds the commands of the app binary.
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewRootCommand returns the app command with every subcommand.
+func NewRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "app",
+		Short:         "I_TV movie catalogue API",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	root.AddCommand(
+		newServeCommand(),
+		newMigrateCommand(),
+		newSeedCommand(),
+		newUserCommand(),
+		newConfigCommand(),
+	)
+
+	return root
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/app"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/movie"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newSeedCommand() *cobra.Command {
+	var (
+		format string
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "seed FILE",
+		Short: "Load fixture movies from a CSV or NDJSON file",
+		Long: "Load fixture movies from a CSV or NDJSON file, in the format of the movie import.\n" +
+			"Movies are upserted on title, so seeding twice updates rather than duplicates.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				switch strings.ToLower(filepath.Ext(args[0])) {
+				case ".csv":
+					format = movie.ImportFormatCSV
+				case ".ndjson", ".jsonl":
+					format = movie.ImportFormatNDJSON
+				default:
+					return errors.Errorf("cannot tell the format of %s, set --format", args[0])
+				}
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			var movies movie.Service
+			if err := app.Populate(&movies); err != nil {
+				return err
+			}
+
+			report, err := movies.Import(file, format, dryRun, audit.System)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for _, row := range report.Rows {
+				if row.Result == movie.ImportRejected {
+					fmt.Fprintf(out, "line %d: %s: %s\n", row.Line, row.Title, row.Reason)
+				}
+			}
+			if report.DryRun {
+				fmt.Fprint(out, "dry run, nothing written: ")
+			}
+			fmt.Fprintf(out, "%d created, %d updated, %d rejected\n", report.Created, report.Updated, report.Rejected)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "file format, csv or ndjson; defaults from the file extension")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate the file without writing anything")
+
+	return cmd
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/app"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/user"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gorm.io/gorm"
+)
+
+func newUserCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage user accounts",
+	}
+
+	cmd.AddCommand(
+		newUserCreateCommand(),
+		newUserResetPasswordCommand(),
+	)
+
+	return cmd
+}
+
+func newUserCreateCommand() *cobra.Command {
+	var (
+		input user.RegisterInput
+		role  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user with a verified email",
+		Long: "Create a user with a verified email. Without --password the password is read\n" +
+			"from the terminal, or from the first line of stdin when it is not one.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if input.Password == "" {
+				password, err := readPassword(cmd)
+				if err != nil {
+					return err
+				}
+				input.Password = password
+			}
+
+			if err := common.Validate.Struct(input); err != nil {
+				return err
+			}
+			if err := common.Validate.Struct(user.AssignRoleInput{Role: role}); err != nil {
+				return errors.Errorf("invalid role %q", role)
+			}
+
+			hashPassword, err := helper.PasswordHash(input.Password)
+			if err != nil {
+				return errors.Wrap(err, "failed to hash password")
+			}
+
+			var users user.Service
+			if err := app.Populate(&users); err != nil {
+				return err
+			}
+
+			now := time.Now()
+			created, err := users.Create(user.User{
+				FirstName:       input.FirstName,
+				LastName:        input.LastName,
+				Email:           input.Email,
+				Password:        hashPassword,
+				Role:            role,
+				EmailVerifiedAt: &now,
+			}, audit.System)
+			if err != nil {
+				if helper.ErrorIs(err, "duplicate") {
+					return user.ErrEmailTaken
+				}
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "created %s user %d\n", role, created.ID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&input.Email, "email", "", "email of the user")
+	cmd.Flags().StringVar(&input.FirstName, "first-name", "", "first name, lowercase")
+	cmd.Flags().StringVar(&input.LastName, "last-name", "", "last name, lowercase")
+	cmd.Flags().StringVar(&input.Password, "password", "", "password; prompted for when not set")
+	cmd.Flags().StringVar(&role, "role", user.RoleViewer, "role: viewer, editor, moderator or admin")
+	cmd.MarkFlagRequired("email")
+	cmd.MarkFlagRequired("first-name")
+	cmd.MarkFlagRequired("last-name")
+
+	return cmd
+}
+
+func newUserResetPasswordCommand() *cobra.Command {
+	var (
+		email    string
+		password string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reset-password",
+		Short: "Set a new password for a user",
+		Long: "Set a new password for a user, signing out all of their sessions and lifting\n" +
+			"a login lockout. Without --password the password is read like for user create.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if password == "" {
+				var err error
+				if password, err = readPassword(cmd); err != nil {
+					return err
+				}
+			}
+
+			if err := common.Validate.Struct(user.SetPasswordInput{Password: password}); err != nil {
+				return err
+			}
+
+			var users user.Service
+			if err := app.Populate(&users); err != nil {
+				return err
+			}
+
+			account, err := users.GetByEmail(email)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.Errorf("no user with email %s", email)
+				}
+				return err
+			}
+
+			if _, err := users.SetPassword(user.SetPasswordInput{ID: account.ID, Password: password}, audit.System); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "password of user %d changed\n", account.ID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&email, "email", "", "email of the user")
+	cmd.Flags().StringVar(&password, "password", "", "new password; prompted for when not set")
+	cmd.MarkFlagRequired("email")
+
+	return cmd
+}
+
+// readPassword prompts for a password without echoing it, or reads the first
+// line of stdin when that is not a terminal, as in `echo secret | app ...`.
+func readPassword(cmd *cobra.Command) (string, error) {
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(cmd.ErrOrStderr())
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read password")
+		}
+		return string(password), nil
+	}
+
+	line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", errors.Wrap(err, "failed to read password")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -16,6 +16,9 @@ type Config struct {
 	App      App      `mapstructure:"app"`
 	Auth     Auth     `mapstructure:"auth"`
 	Postgres Postgres `mapstructure:"postgres"`
+	Mail     Mail     `mapstructure:"mail"`
+	Storage  Storage  `mapstructure:"storage"`
+	Trash    Trash    `mapstructure:"trash"`
 }
 
 type App struct {
@@ -29,6 +32,11 @@ type App struct {
 	ReadTimeout  time.Duration `envconfig:"APP_READ_TIMEOUT" default:"10s" mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `envconfig:"APP_WRITE_TIMEOUT" default:"10s" mapstructure:"write_timeout"`
 	IdleTimeout  time.Duration `envconfig:"APP_IDLE_TIMEOUT" default:"120s" mapstructure:"idle_timeout"`
+
+	// TrustedProxies lists the addresses or CIDRs of the reverse proxies whose
+	// X-Forwarded-For header is believed. None are trusted by default, so the
+	// client IP is the peer address of the connection.
+	TrustedProxies []string `envconfig:"APP_TRUSTED_PROXIES" mapstructure:"trusted_proxies"`
 }
 
 type Auth struct {
@@ -36,21 +44,68 @@ type Auth struct {
 	RefreshTTL       time.Duration `envconfig:"AUTH_REFRESH_TTL" default:"2h" required:"true" mapstructure:"refresh_ttl"`
 	OtpTTL           time.Duration `envconfig:"AUTH_OTP_TTL" default:"1m" required:"true" mapstructure:"otp_ttl"`
 	CodeLength       int           `envconfig:"AUTH_CODE_LENGTH" default:"6" required:"true" mapstructure:"code_length"`
-	SecretKey        string        `envconfig:"AUTH_KEY" default:"auth_secret_key" required:"true" mapstructure:"secret_key"`
+	OtpMaxAttempts   int           `envconfig:"AUTH_OTP_MAX_ATTEMPTS" default:"5" mapstructure:"otp_max_attempts"`
+	OtpResendDelay   time.Duration `envconfig:"AUTH_OTP_RESEND_DELAY" default:"30s" mapstructure:"otp_resend_delay"`
+	SecretKey        string        `envconfig:"AUTH_KEY" default:"auth_secret_key" required:"true" mapstructure:"secret_key" secret:"true"`
 	MaxLoginAttempts int           `envconfig:"AUTH_MAX_LOGIN_ATTEMPTS" default:"5" mapstructure:"max_login_attempts"`
+	MaxIPAttempts    int           `envconfig:"AUTH_MAX_IP_LOGIN_ATTEMPTS" default:"20" mapstructure:"max_ip_login_attempts"`
 	LockoutDuration  time.Duration `envconfig:"AUTH_LOCKOUT_DURATION" default:"15m" mapstructure:"lockout_duration"`
+	RevocationTTL    time.Duration `envconfig:"AUTH_REVOCATION_CACHE_TTL" default:"30s" mapstructure:"revocation_cache_ttl"`
+	AdminEmail       string        `envconfig:"AUTH_ADMIN_EMAIL" mapstructure:"admin_email"`
+	AdminPassword    string        `envconfig:"AUTH_ADMIN_PASSWORD" mapstructure:"admin_password" secret:"true"`
+	PasswordResetTTL time.Duration `envconfig:"AUTH_PASSWORD_RESET_TTL" default:"15m" mapstructure:"password_reset_ttl"`
+	PasswordResetURL string        `envconfig:"AUTH_PASSWORD_RESET_URL" mapstructure:"password_reset_url"`
 }
 
 type Postgres struct {
 	Host            string        `envconfig:"POSTGRES_HOST" default:"localhost" required:"true" mapstructure:"host"`
 	Port            int           `envconfig:"POSTGRES_PORT" default:"5432" required:"true" mapstructure:"port"`
 	User            string        `envconfig:"POSTGRES_USER" default:"postgres" required:"true" mapstructure:"user"`
-	Password        string        `envconfig:"POSTGRES_PASSWORD" default:"password" required:"true" mapstructure:"password"`
+	Password        string        `envconfig:"POSTGRES_PASSWORD" default:"password" required:"true" mapstructure:"password" secret:"true"`
 	Database        string        `envconfig:"POSTGRES_DATABASE" default:"i_tv_task" required:"true" mapstructure:"database"`
 	SslMode         string        `envconfig:"POSTGRES_SSLMODE" default:"disable" required:"true" mapstructure:"sslmode"`
 	MaxOpenConns    int           `envconfig:"POSTGRES_MAX_OPEN_CONNS" default:"25" mapstructure:"max_open_conns"`
 	MaxIdleConns    int           `envconfig:"POSTGRES_MAX_IDLE_CONNS" default:"5" mapstructure:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `envconfig:"POSTGRES_CONN_MAX_LIFETIME" default:"5m" mapstructure:"conn_max_lifetime"`
+
+	// AutoMigrate syncs the schema with the models on start instead of
+	// relying on `app migrate`. It only applies in the dev environment.
+	AutoMigrate bool `envconfig:"POSTGRES_AUTO_MIGRATE" default:"false" mapstructure:"auto_migrate"`
+}
+
+type Mail struct {
+	Driver string `envconfig:"MAIL_DRIVER" default:"log" mapstructure:"driver"`
+	From   string `envconfig:"MAIL_FROM" default:"no-reply@localhost" mapstructure:"from"`
+	Dir    string `envconfig:"MAIL_DIR" default:"tmp/mail" mapstructure:"dir"`
+
+	SMTPHost     string `envconfig:"MAIL_SMTP_HOST" mapstructure:"smtp_host"`
+	SMTPPort     int    `envconfig:"MAIL_SMTP_PORT" default:"587" mapstructure:"smtp_port"`
+	SMTPUsername string `envconfig:"MAIL_SMTP_USERNAME" mapstructure:"smtp_username"`
+	SMTPPassword string `envconfig:"MAIL_SMTP_PASSWORD" mapstructure:"smtp_password" secret:"true"`
+}
+
+// Storage configures where uploaded files are kept. PublicURL prefixes the
+// file URLs in responses and defaults to paths relative to this API.
+type Storage struct {
+	Driver        string `envconfig:"STORAGE_DRIVER" default:"local" mapstructure:"driver"`
+	Dir           string `envconfig:"STORAGE_DIR" default:"tmp/storage" mapstructure:"dir"`
+	PublicURL     string `envconfig:"STORAGE_PUBLIC_URL" mapstructure:"public_url"`
+	MaxUploadSize int64  `envconfig:"STORAGE_MAX_UPLOAD_SIZE" default:"10485760" mapstructure:"max_upload_size"`
+
+	S3Endpoint  string `envconfig:"STORAGE_S3_ENDPOINT" mapstructure:"s3_endpoint"`
+	S3Region    string `envconfig:"STORAGE_S3_REGION" default:"us-east-1" mapstructure:"s3_region"`
+	S3Bucket    string `envconfig:"STORAGE_S3_BUCKET" mapstructure:"s3_bucket"`
+	S3AccessKey string `envconfig:"STORAGE_S3_ACCESS_KEY" mapstructure:"s3_access_key" secret:"true"`
+	S3SecretKey string `envconfig:"STORAGE_S3_SECRET_KEY" mapstructure:"s3_secret_key" secret:"true"`
+	S3PathStyle bool   `envconfig:"STORAGE_S3_PATH_STYLE" default:"true" mapstructure:"s3_path_style"`
+}
+
+// Trash configures how long deleted movies can be restored before the purge
+// job removes them for good. Zero RetentionDays keeps them until purged by
+// hand.
+type Trash struct {
+	RetentionDays int           `envconfig:"TRASH_RETENTION_DAYS" default:"30" mapstructure:"retention_days"`
+	PurgeInterval time.Duration `envconfig:"TRASH_PURGE_INTERVAL" default:"1h" mapstructure:"purge_interval"`
 }
 
 const configDir = "config"
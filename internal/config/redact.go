@@ -0,0 +1,37 @@
+package config
+
+import (
+	"reflect"
+	"time"
+)
+
+const redacted = "[redacted]"
+
+// Redacted returns the config as nested maps keyed like the config file. Set
+// values of fields tagged secret:"true" are replaced, so the result is safe to
+// print.
+func (c *Config) Redacted() map[string]interface{} {
+	return redact(reflect.ValueOf(*c))
+}
+
+func redact(v reflect.Value) map[string]interface{} {
+	t := v.Type()
+	out := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		value := v.Field(i)
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			out[name] = redact(value)
+		case field.Tag.Get("secret") == "true" && !value.IsZero():
+			out[name] = redacted
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			out[name] = value.Interface().(time.Duration).String()
+		default:
+			out[name] = value.Interface()
+		}
+	}
+	return out
+}
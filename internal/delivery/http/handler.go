@@ -2,13 +2,16 @@ package http
 
 import (
 	"net/http"
+	"regexp"
 	"time"
 
 	"github.com/asliddinberdiev/i_tv_task/docs"
 	"github.com/asliddinberdiev/i_tv_task/internal/config"
 	v1 "github.com/asliddinberdiev/i_tv_task/internal/delivery/http/v1"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
 	logger "github.com/asliddinberdiev/i_tv_task/pkgs/logger/zap"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/fx"
@@ -30,9 +33,15 @@ type HandlerParams struct {
 	V1  *v1.V1Routes
 }
 
-func NewHandler(params HandlerParams) *Handler {
+func NewHandler(params HandlerParams) (*Handler, error) {
 	router := gin.New()
 
+	// ClientIP keys the login lockout, so forwarding headers are only taken
+	// from the configured proxies.
+	if err := router.SetTrustedProxies(params.Cfg.App.TrustedProxies); err != nil {
+		return nil, errors.Wrap(err, "invalid trusted proxies")
+	}
+
 	handler := &Handler{
 		Router: router,
 		cfg:    params.Cfg,
@@ -41,7 +50,7 @@ func NewHandler(params HandlerParams) *Handler {
 
 	handler.Setup(params.V1)
 
-	return handler
+	return handler, nil
 }
 
 // @title I_TV API
@@ -57,6 +66,7 @@ func NewHandler(params HandlerParams) *Handler {
 func (h *Handler) Setup(v1Routes *v1.V1Routes) {
 	h.Router.Use(
 		gin.Recovery(),
+		h.requestIDMiddleware(),
 		h.loggingMiddleware(),
 		h.corsMiddleware(),
 	)
@@ -90,15 +100,41 @@ func (h *Handler) loggingMiddleware() gin.HandlerFunc {
 			logger.String("path", path),
 			logger.Int("status", c.Writer.Status()),
 			logger.String("time", time.Since(start).String()),
+			logger.String("request_id", c.GetString("request_id")),
 		)
 	}
 }
 
+// requestIDPattern is what an X-Request-ID sent by the client must look like
+// to be kept; anything else is replaced, so it is safe to log.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestIDMiddleware gives every request an ID, taken from X-Request-ID when
+// the client sends a valid one, and echoes it in the response.
+func (h *Handler) requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			var err error
+			if id, err = helper.RandomHex(16); err != nil {
+				h.log.Error("failed to generate request id", logger.Error(err))
+				id = ""
+			}
+		}
+
+		c.Set("request_id", id)
+		c.Writer.Header().Set("X-Request-ID", id)
+
+		c.Next()
+	}
+}
+
 func (h *Handler) corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Disposition, ETag, X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusOK)
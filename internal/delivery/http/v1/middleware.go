@@ -11,7 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func jwtMiddleware(cfg *config.Config) gin.HandlerFunc {
+func jwtMiddleware(cfg *config.Config, revocations user.RevocationStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		if token == "" {
@@ -47,7 +47,84 @@ func jwtMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if claims.Type != user.TokenTypeAccess {
+			c.AbortWithStatusJSON(
+				http.StatusUnauthorized,
+				common.ResponseError{
+					Status:  http.StatusUnauthorized,
+					Message: "Invalid token type",
+				},
+			)
+			return
+		}
+
+		revoked, err := revocations.IsRevoked(claims)
+		if err != nil {
+			c.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				common.ResponseError{
+					Status:  http.StatusInternalServerError,
+					Message: "Failed to check token",
+				},
+			)
+			return
+		}
+
+		if revoked {
+			c.AbortWithStatusJSON(
+				http.StatusUnauthorized,
+				common.ResponseError{
+					Status:  http.StatusUnauthorized,
+					Message: "Token has been revoked",
+				},
+			)
+			return
+		}
+
 		c.Set("user_id", claims.ID)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// optionalJwtMiddleware authenticates the request like jwtMiddleware when an
+// Authorization header is sent, and lets anonymous requests through.
+func optionalJwtMiddleware(cfg *config.Config, revocations user.RevocationStore) gin.HandlerFunc {
+	authenticate := jwtMiddleware(cfg, revocations)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+func roleMiddleware(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := c.Get("claims")
+		if !ok {
+			c.AbortWithStatusJSON(
+				http.StatusUnauthorized,
+				common.ResponseError{
+					Status:  http.StatusUnauthorized,
+					Message: "Unauthorized",
+				},
+			)
+			return
+		}
+
+		if !claims.(user.UserClaims).HasRole(role) {
+			c.AbortWithStatusJSON(
+				http.StatusForbidden,
+				common.ResponseError{
+					Status:  http.StatusForbidden,
+					Message: "Insufficient role",
+				},
+			)
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -2,8 +2,16 @@ package v1
 
 import (
 	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/artwork"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/credit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/genre"
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/movie"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/rating"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/review"
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/user"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/watchlist"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/fx"
 )
@@ -11,25 +19,52 @@ import (
 var Module = fx.Module("delivery_http_v1", fx.Provide(NewV1Routes))
 
 type V1Routes struct {
-	cfg    *config.Config
-	users  user.Handler
-	movies movie.Handler
+	cfg         *config.Config
+	revocations user.RevocationStore
+	users       user.Handler
+	movies      movie.Handler
+	genres      genre.Handler
+	people      person.Handler
+	credits     credit.Handler
+	ratings     rating.Handler
+	reviews     review.Handler
+	watchlist   watchlist.Handler
+	artwork     artwork.Handler
+	audit       audit.Handler
 }
 
 type V1RoutesParams struct {
 	fx.In
-	Cfg *config.Config
+	Cfg         *config.Config
+	Revocations user.RevocationStore
 
-	Users  user.Handler
-	Movies movie.Handler
+	Users     user.Handler
+	Movies    movie.Handler
+	Genres    genre.Handler
+	People    person.Handler
+	Credits   credit.Handler
+	Ratings   rating.Handler
+	Reviews   review.Handler
+	Watchlist watchlist.Handler
+	Artwork   artwork.Handler
+	Audit     audit.Handler
 }
 
 func NewV1Routes(params V1RoutesParams) *V1Routes {
 	return &V1Routes{
-		cfg: params.Cfg,
+		cfg:         params.Cfg,
+		revocations: params.Revocations,
 
-		users:  params.Users,
-		movies: params.Movies,
+		users:     params.Users,
+		movies:    params.Movies,
+		genres:    params.Genres,
+		people:    params.People,
+		credits:   params.Credits,
+		ratings:   params.Ratings,
+		reviews:   params.Reviews,
+		watchlist: params.Watchlist,
+		artwork:   params.Artwork,
+		audit:     params.Audit,
 	}
 }
 
@@ -40,26 +75,140 @@ func (h *V1Routes) SetupPublicRoutes(api *gin.RouterGroup) {
 		{
 			users.POST("/register", h.users.Register)
 			users.POST("/login", h.users.Login)
+			users.POST("/refresh", h.users.Refresh)
+			users.POST("/otp", h.users.RequestCode)
+			users.POST("/verify-email", h.users.VerifyEmail)
+			users.POST("/login/otp", h.users.LoginWithCode)
+			users.POST("/password/forgot", h.users.ForgotPassword)
+			users.POST("/password/reset", h.users.ResetPassword)
+			users.GET("/:id/reviews", h.reviews.ListByUser)
 		}
 
 		movies := v1Group.Group("/movies")
 		{
-			movies.GET("", h.movies.GetAll)
+			movies.GET("", optionalJwtMiddleware(h.cfg, h.revocations), h.movies.GetAll)
 			movies.GET("/:id", h.movies.GetByID)
+			movies.GET("/:id/credits", h.credits.ListByMovie)
+			movies.GET("/:id/ratings", h.ratings.Summary)
+			movies.GET("/:id/reviews", h.reviews.ListByMovie)
+		}
+
+		v1Group.GET("/artwork/:id/:variant", h.artwork.Serve)
+
+		reviews := v1Group.Group("/reviews")
+		{
+			reviews.GET("/:id", h.reviews.GetByID)
+		}
+
+		genres := v1Group.Group("/genres")
+		{
+			genres.GET("", h.genres.GetAll)
+			genres.GET("/:id", h.genres.GetByID)
+		}
+
+		people := v1Group.Group("/people")
+		{
+			people.GET("", h.people.GetAll)
+			people.GET("/:id", h.people.GetByID)
+			people.GET("/:id/filmography", h.credits.ListByPerson)
 		}
 	}
 }
 
 func (h *V1Routes) SetupPrivateRoutes(api *gin.RouterGroup) {
 	v1Group := api.Group("/v1")
-	v1Group.Use(jwtMiddleware(h.cfg))
+	v1Group.Use(jwtMiddleware(h.cfg, h.revocations))
 	{
+		users := v1Group.Group("/users")
+		{
+			users.POST("/logout", h.users.Logout)
+			users.POST("/logout-all", h.users.LogoutAll)
+		}
+
+		admin := v1Group.Group("/admin")
+		admin.Use(roleMiddleware(user.RoleAdmin))
+		{
+			admin.POST("/users/:id/unlock", h.users.Unlock)
+			admin.PUT("/users/:id/role", h.users.AssignRole)
+			admin.GET("/users/:id/history", h.audit.UserHistory)
+
+			admin.GET("/trash/movies", h.movies.ListTrash)
+			admin.POST("/trash/movies/:id/restore", h.movies.Restore)
+			admin.DELETE("/trash/movies/:id", h.movies.Purge)
+
+			admin.GET("/audit", h.audit.List)
+		}
 
 		movies := v1Group.Group("/movies")
 		{
-			movies.POST("", h.movies.Create)
-			movies.PUT("/:id", h.movies.Update)
-			movies.DELETE("/:id", h.movies.Delete)
+			movies.POST("", roleMiddleware(user.RoleEditor), h.movies.Create)
+			movies.POST("/import", roleMiddleware(user.RoleEditor), h.movies.Import)
+			movies.GET("/export", h.movies.Export)
+			movies.PUT("/:id", roleMiddleware(user.RoleEditor), h.movies.Update)
+			movies.PATCH("/:id", roleMiddleware(user.RoleEditor), h.movies.Patch)
+			movies.DELETE("/:id", roleMiddleware(user.RoleAdmin), h.movies.Delete)
+			movies.GET("/:id/history", roleMiddleware(user.RoleEditor), h.audit.MovieHistory)
+
+			movies.PUT("/:id/images/:kind", roleMiddleware(user.RoleEditor), h.artwork.Upload)
+			movies.DELETE("/:id/images/:kind", roleMiddleware(user.RoleEditor), h.artwork.Delete)
+
+			movies.POST("/:id/credits", roleMiddleware(user.RoleEditor), h.credits.Create)
+			movies.PUT("/:id/credits/:credit_id", roleMiddleware(user.RoleEditor), h.credits.Update)
+			movies.DELETE("/:id/credits/:credit_id", roleMiddleware(user.RoleEditor), h.credits.Delete)
+
+			movies.GET("/:id/rating", h.ratings.Get)
+			movies.PUT("/:id/rating", h.ratings.Rate)
+			movies.DELETE("/:id/rating", h.ratings.Delete)
+
+			movies.POST("/:id/reviews", h.reviews.Create)
+		}
+
+		reviews := v1Group.Group("/reviews")
+		{
+			reviews.PUT("/:id", h.reviews.Update)
+			reviews.DELETE("/:id", h.reviews.Delete)
+			reviews.POST("/:id/reports", h.reviews.Report)
+		}
+
+		me := v1Group.Group("/me")
+		{
+			me.GET("", h.users.Me)
+			me.PATCH("", h.users.UpdateMe)
+			me.DELETE("", h.users.DeleteMe)
+			me.POST("/password", h.users.ChangePassword)
+			me.POST("/email", h.users.RequestEmailChange)
+			me.POST("/email/verify", h.users.ConfirmEmailChange)
+
+			me.GET("/reviews", h.reviews.ListMine)
+
+			me.GET("/watchlist", h.watchlist.List)
+			me.PUT("/watchlist/:movie_id", h.watchlist.Add)
+			me.DELETE("/watchlist/:movie_id", h.watchlist.Remove)
+
+			me.GET("/history", h.watchlist.History)
+			me.POST("/history", h.watchlist.LogWatch)
+			me.DELETE("/history/:id", h.watchlist.DeleteWatch)
+		}
+
+		moderation := v1Group.Group("/moderation")
+		moderation.Use(roleMiddleware(user.RoleModerator))
+		{
+			moderation.GET("/reviews", h.reviews.ListForModeration)
+			moderation.PUT("/reviews/:id", h.reviews.Moderate)
+		}
+
+		genres := v1Group.Group("/genres")
+		{
+			genres.POST("", roleMiddleware(user.RoleEditor), h.genres.Create)
+			genres.PUT("/:id", roleMiddleware(user.RoleEditor), h.genres.Update)
+			genres.DELETE("/:id", roleMiddleware(user.RoleAdmin), h.genres.Delete)
+		}
+
+		people := v1Group.Group("/people")
+		{
+			people.POST("", roleMiddleware(user.RoleEditor), h.people.Create)
+			people.PUT("/:id", roleMiddleware(user.RoleEditor), h.people.Update)
+			people.DELETE("/:id", roleMiddleware(user.RoleAdmin), h.people.Delete)
 		}
 	}
 }
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	logger "github.com/asliddinberdiev/i_tv_task/pkgs/logger/zap"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/mail"
+	"github.com/pkg/errors"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Module("mailer", fx.Provide(NewSender))
+
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+// NewSender builds the sender named by cfg.Mail.Driver. The log driver writes
+// codes and reset tokens to the application log, so it is refused outside the
+// dev environment.
+func NewSender(cfg *config.Config, log logger.Logger) (mail.Sender, error) {
+	switch cfg.Mail.Driver {
+	case DriverLog, "":
+		if cfg.App.Environment != "dev" {
+			return nil, errors.Errorf("the %s mail driver is only allowed in the dev environment; set MAIL_DRIVER", DriverLog)
+		}
+		return mail.NewLogSender(log), nil
+	case DriverFile:
+		return mail.NewFileSender(cfg.Mail.Dir, cfg.Mail.From)
+	case DriverSMTP:
+		return mail.NewSMTPSender(mail.SMTPConfig{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.SMTPUsername,
+			Password: cfg.Mail.SMTPPassword,
+			From:     cfg.Mail.From,
+		})
+	default:
+		return nil, errors.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
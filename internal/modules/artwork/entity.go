@@ -0,0 +1,74 @@
+package artwork
+
+import "time"
+
+const (
+	KindPoster   = "poster"
+	KindBackdrop = "backdrop"
+
+	// VariantOriginal is the uploaded file, next to the width variants.
+	VariantOriginal = "original"
+)
+
+// kindWidths are the thumbnail widths generated per kind, largest first.
+var kindWidths = map[string][]int{
+	KindPoster:   {500, 342, 185},
+	KindBackdrop: {1280, 780, 300},
+}
+
+// Image is the current poster or backdrop of a movie. Its files live in the
+// blob store under Key, one per variant. A new upload replaces the row, so
+// an image ID always refers to the same files.
+type Image struct {
+	ID          uint   `gorm:"primaryKey"`
+	MovieID     uint   `gorm:"not null;uniqueIndex:idx_movie_images_movie_kind"`
+	Kind        string `gorm:"type:varchar(16);not null;uniqueIndex:idx_movie_images_movie_kind"`
+	Key         string `gorm:"type:varchar(255);not null"`
+	ContentType string `gorm:"type:varchar(64);not null"`
+	Size        int64  `gorm:"not null"`
+	Width       int    `gorm:"not null"`
+	Height      int    `gorm:"not null"`
+	Checksum    string `gorm:"type:varchar(64);not null"`
+	CreatedAt   time.Time
+}
+
+func (Image) TableName() string {
+	return "movie_images"
+}
+
+type UploadInput struct {
+	MovieID     uint
+	Kind        string `validate:"oneof=poster backdrop"`
+	ContentType string `validate:"oneof=image/jpeg image/png"`
+	Data        []byte
+}
+
+// URLs maps each variant of an image to its URL.
+type URLs map[string]string
+
+type ImageResponse struct {
+	ID          uint      `json:"id"`
+	MovieID     uint      `json:"movie_id"`
+	Kind        string    `json:"kind"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	URLs        URLs      `json:"urls"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// MovieImages is what movie responses embed.
+type MovieImages struct {
+	Poster   URLs `json:"poster,omitempty"`
+	Backdrop URLs `json:"backdrop,omitempty"`
+}
+
+// Object describes one variant of an image for serving.
+type Object struct {
+	ContentType string
+	ETag        string
+	ModTime     time.Time
+
+	key string
+}
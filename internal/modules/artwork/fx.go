@@ -0,0 +1,12 @@
+package artwork
+
+import "go.uber.org/fx"
+
+var Module = fx.Module(
+	"artwork_module",
+	fx.Provide(
+		NewRepository,
+		NewService,
+		NewHandler,
+	),
+)
@@ -0,0 +1,367 @@
+package artwork
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Handler interface {
+	Upload(c *gin.Context)
+	Delete(c *gin.Context)
+	Serve(c *gin.Context)
+}
+
+type handler struct {
+	service Service
+	cfg     *config.Config
+}
+
+func NewHandler(service Service, cfg *config.Config) Handler {
+	return &handler{service: service, cfg: cfg}
+}
+
+// multipartOverhead is allowed on top of the upload size limit for the
+// boundaries and part headers of the form.
+const multipartOverhead = 64 << 10
+
+// @Summary Upload a movie image
+// @Description Upload the poster or backdrop of a movie as a JPEG or PNG file, replacing the current one. Thumbnails are generated in several widths; posters get w185, w342 and w500 and backdrops w300, w780 and w1280
+// @Tags movies
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param kind path string true "Image kind" Enums(poster, backdrop)
+// @Param file formData file true "JPEG or PNG image"
+// @Success 200 {object} common.Response{data=artwork.ImageResponse}
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 413 {object} common.ResponseError
+// @Failure 415 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/images/{kind} [put]
+func (h *handler) Upload(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	maxSize := h.cfg.Storage.MaxUploadSize
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+multipartOverhead)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(
+				http.StatusRequestEntityTooLarge,
+				common.ResponseError{
+					Status:  http.StatusRequestEntityTooLarge,
+					Message: "Image file is too large",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Missing image file",
+			},
+		)
+		return
+	}
+
+	if header.Size > maxSize {
+		c.JSON(
+			http.StatusRequestEntityTooLarge,
+			common.ResponseError{
+				Status:  http.StatusRequestEntityTooLarge,
+				Message: "Image file is too large",
+			},
+		)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req := UploadInput{
+		MovieID: uint(movieID),
+		Kind:    c.Param("kind"),
+		// The declared type of the part is not trusted, the content decides.
+		ContentType: http.DetectContentType(data),
+		Data:        data,
+	}
+
+	if err := common.Validate.Var(req.Kind, "oneof=poster backdrop"); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Image kind must be poster or backdrop",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusUnsupportedMediaType,
+			common.ResponseError{
+				Status:  http.StatusUnsupportedMediaType,
+				Message: ErrUnsupportedImage.Error(),
+			},
+		)
+		return
+	}
+
+	image, err := h.service.Upload(req)
+	if err != nil {
+		if errors.Is(err, ErrMovieNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie not found",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrUnsupportedImage) {
+			c.JSON(
+				http.StatusUnsupportedMediaType,
+				common.ResponseError{
+					Status:  http.StatusUnsupportedMediaType,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrTooManyPixels) {
+			c.JSON(
+				http.StatusRequestEntityTooLarge,
+				common.ResponseError{
+					Status:  http.StatusRequestEntityTooLarge,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "Image uploaded successfully",
+			Data:    image,
+		},
+	)
+}
+
+// @Summary Delete a movie image
+// @Description Delete the poster or backdrop of a movie together with its thumbnails
+// @Tags movies
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param kind path string true "Image kind" Enums(poster, backdrop)
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/images/{kind} [delete]
+func (h *handler) Delete(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	image, err := h.service.Delete(uint(movieID), c.Param("kind"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Image not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Image deleted successfully",
+			ID:      image.ID,
+		},
+	)
+}
+
+// @Summary Get a movie image
+// @Description Serve one variant of a movie image. Image URLs never change content, so responses may be cached indefinitely and are revalidated with ETag
+// @Tags artwork
+// @Produce image/jpeg
+// @Produce image/png
+// @Param id path string true "Image ID"
+// @Param variant path string true "original, or a thumbnail width such as w342"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {file} file
+// @Success 304
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/artwork/{id}/{variant} [get]
+func (h *handler) Serve(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid image id",
+			},
+		)
+		return
+	}
+
+	object, err := h.service.Stat(uint(id), c.Param("variant"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrVariantNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Image not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.Header("ETag", object.ETag)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("Last-Modified", object.ModTime.UTC().Format(http.TimeFormat))
+
+	if match := c.GetHeader("If-None-Match"); match != "" && (match == "*" || containsETag(match, object.ETag)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	body, err := h.service.Open(object)
+	if err != nil {
+		if errors.Is(err, ErrVariantNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Image not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, -1, object.ContentType, body, nil)
+}
+
+// containsETag reports whether an If-None-Match list names etag, using the
+// weak comparison the header calls for.
+func containsETag(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package artwork
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	Replace(image *Image) (*Image, error)
+	Delete(movieID uint, kind string) (*Image, error)
+	DeleteByMovies(tx *gorm.DB, movieIDs []uint) ([]Image, error)
+	GetByID(id uint) (*Image, error)
+	ListByMovies(movieIDs []uint) ([]Image, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(psql postgres.PostgresDB) Repository {
+	return &repository{db: psql.DB()}
+}
+
+// Replace stores the image as the one of its kind for the movie and returns
+// the image it replaced, if any.
+func (r *repository) Replace(image *Image) (*Image, error) {
+	var previous *Image
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var old []Image
+		if err := tx.Clauses(clause.Returning{}).
+			Where("movie_id = ? AND kind = ?", image.MovieID, image.Kind).
+			Delete(&old).Error; err != nil {
+			return err
+		}
+		if len(old) > 0 {
+			previous = &old[0]
+		}
+		return tx.Create(image).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+func (r *repository) Delete(movieID uint, kind string) (*Image, error) {
+	var deleted []Image
+	if err := r.db.Clauses(clause.Returning{}).
+		Where("movie_id = ? AND kind = ?", movieID, kind).
+		Delete(&deleted).Error; err != nil {
+		return nil, err
+	}
+	if len(deleted) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &deleted[0], nil
+}
+
+// DeleteByMovies removes the images of the movies within tx, the transaction
+// that purges them, and returns what it removed.
+func (r *repository) DeleteByMovies(tx *gorm.DB, movieIDs []uint) ([]Image, error) {
+	deleted := make([]Image, 0)
+	if len(movieIDs) == 0 {
+		return deleted, nil
+	}
+	if err := tx.Clauses(clause.Returning{}).
+		Where("movie_id IN ?", movieIDs).
+		Delete(&deleted).Error; err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+func (r *repository) GetByID(id uint) (*Image, error) {
+	var image Image
+	if err := r.db.First(&image, id).Error; err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (r *repository) ListByMovies(movieIDs []uint) ([]Image, error) {
+	images := make([]Image, 0)
+	if len(movieIDs) == 0 {
+		return images, nil
+	}
+	if err := r.db.Where("movie_id IN ?", movieIDs).Find(&images).Error; err != nil {
+		return nil, err
+	}
+	return images, nil
+}
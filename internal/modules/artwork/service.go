@@ -0,0 +1,291 @@
+package artwork
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/blob"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
+	logger "github.com/asliddinberdiev/i_tv_task/pkgs/logger/zap"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	Upload(req UploadInput) (*ImageResponse, error)
+	Delete(movieID uint, kind string) (*common.ResponseID, error)
+	DeleteForMovies(tx *gorm.DB, movieIDs []uint) ([]Image, error)
+	DeleteFiles(images []Image)
+	Stat(id uint, variant string) (*Object, error)
+	Open(object *Object) (io.ReadCloser, error)
+	ForMovies(movieIDs []uint) (map[uint]*MovieImages, error)
+}
+
+var (
+	ErrMovieNotFound    = errors.New("movie not found")
+	ErrUnsupportedImage = errors.New("only JPEG and PNG images are accepted")
+	ErrTooManyPixels    = errors.New("image dimensions are too large")
+	ErrVariantNotFound  = errors.New("image variant not found")
+)
+
+const (
+	// maxPixels bounds the decoded size of an upload, which takes four bytes
+	// per pixel in memory.
+	maxPixels = 25_000_000
+
+	thumbnailQuality = 85
+)
+
+type service struct {
+	repo   Repository
+	movies common.MovieChecker
+	store  blob.Store
+	log    logger.Logger
+	cfg    *config.Config
+}
+
+func NewService(repo Repository, movies common.MovieChecker, store blob.Store, log logger.Logger, cfg *config.Config) Service {
+	return &service{repo: repo, movies: movies, store: store, log: log, cfg: cfg}
+}
+
+// Upload stores the image with its thumbnails and makes it the image of its
+// kind for the movie. The files of the image it replaces are removed after
+// the swap, so responses never point at missing files.
+func (s *service) Upload(req UploadInput) (*ImageResponse, error) {
+	exists, err := s.movies.MovieExists(req.MovieID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrMovieNotFound
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(req.Data))
+	if err != nil || "image/"+format != req.ContentType {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(req.Data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	token, err := helper.RandomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(req.Data)
+	img := Image{
+		MovieID:     req.MovieID,
+		Kind:        req.Kind,
+		Key:         fmt.Sprintf("movies/%d/%s-%s", req.MovieID, req.Kind, token),
+		ContentType: req.ContentType,
+		Size:        int64(len(req.Data)),
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		Checksum:    hex.EncodeToString(sum[:]),
+	}
+
+	written, err := s.putVariants(img, req.Data, src)
+	if err != nil {
+		s.deleteObjects(written)
+		return nil, err
+	}
+
+	previous, err := s.repo.Replace(&img)
+	if err != nil {
+		s.deleteObjects(written)
+		return nil, err
+	}
+	if previous != nil {
+		s.deleteObjects(objectKeys(*previous))
+	}
+
+	return s.response(img), nil
+}
+
+// putVariants writes the original and every thumbnail, each one scaled from
+// the previous, larger one. It returns the keys written so far, also on
+// error.
+func (s *service) putVariants(img Image, original []byte, src image.Image) ([]string, error) {
+	written := make([]string, 0, len(kindWidths[img.Kind])+1)
+
+	key := objectKey(img.Key, VariantOriginal)
+	if err := s.store.Put(key, bytes.NewReader(original), int64(len(original)), img.ContentType); err != nil {
+		return written, err
+	}
+	written = append(written, key)
+
+	var buf bytes.Buffer
+	for _, width := range kindWidths[img.Kind] {
+		thumb := thumbnail(src, width)
+		src = thumb
+
+		buf.Reset()
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return written, err
+		}
+
+		key := objectKey(img.Key, widthVariant(width))
+		if err := s.store.Put(key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/jpeg"); err != nil {
+			return written, err
+		}
+		written = append(written, key)
+	}
+	return written, nil
+}
+
+func (s *service) Delete(movieID uint, kind string) (*common.ResponseID, error) {
+	img, err := s.repo.Delete(movieID, kind)
+	if err != nil {
+		return nil, err
+	}
+	s.deleteObjects(objectKeys(*img))
+	return &common.ResponseID{ID: img.ID}, nil
+}
+
+// DeleteForMovies removes every image of the movies within tx, the
+// transaction that purges them. The files stay until the caller passes the
+// returned images to DeleteFiles once tx has committed.
+func (s *service) DeleteForMovies(tx *gorm.DB, movieIDs []uint) ([]Image, error) {
+	return s.repo.DeleteByMovies(tx, movieIDs)
+}
+
+// DeleteFiles removes the files of images that are gone from the database.
+func (s *service) DeleteFiles(images []Image) {
+	for _, img := range images {
+		s.deleteObjects(objectKeys(img))
+	}
+}
+
+// deleteObjects removes files that are no longer referenced. Failures only
+// leave orphaned files behind, so they are logged rather than returned.
+func (s *service) deleteObjects(keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			s.log.Warn("artwork: failed to delete object", logger.String("key", key), logger.Error(err))
+		}
+	}
+}
+
+func (s *service) Stat(id uint, variant string) (*Object, error) {
+	img, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !hasVariant(img.Kind, variant) {
+		return nil, ErrVariantNotFound
+	}
+
+	contentType := "image/jpeg"
+	if variant == VariantOriginal {
+		contentType = img.ContentType
+	}
+
+	return &Object{
+		ContentType: contentType,
+		ETag:        fmt.Sprintf(`"%s-%s"`, img.Checksum, variant),
+		ModTime:     img.CreatedAt,
+		key:         objectKey(img.Key, variant),
+	}, nil
+}
+
+func (s *service) Open(object *Object) (io.ReadCloser, error) {
+	body, err := s.store.Get(object.key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, ErrVariantNotFound
+	}
+	return body, err
+}
+
+// ForMovies returns the image URLs of the given movies. Movies without
+// images are left out.
+func (s *service) ForMovies(movieIDs []uint) (map[uint]*MovieImages, error) {
+	images, err := s.repo.ListByMovies(movieIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byMovie := make(map[uint]*MovieImages, len(images))
+	for _, img := range images {
+		urls, ok := byMovie[img.MovieID]
+		if !ok {
+			urls = &MovieImages{}
+			byMovie[img.MovieID] = urls
+		}
+
+		switch img.Kind {
+		case KindPoster:
+			urls.Poster = s.urls(img)
+		case KindBackdrop:
+			urls.Backdrop = s.urls(img)
+		}
+	}
+	return byMovie, nil
+}
+
+func (s *service) response(img Image) *ImageResponse {
+	return &ImageResponse{
+		ID:          img.ID,
+		MovieID:     img.MovieID,
+		Kind:        img.Kind,
+		ContentType: img.ContentType,
+		Size:        img.Size,
+		Width:       img.Width,
+		Height:      img.Height,
+		URLs:        s.urls(img),
+		CreatedAt:   img.CreatedAt,
+	}
+}
+
+func (s *service) urls(img Image) URLs {
+	base := fmt.Sprintf("%s/api/v1/artwork/%d/", strings.TrimRight(s.cfg.Storage.PublicURL, "/"), img.ID)
+
+	urls := URLs{VariantOriginal: base + VariantOriginal}
+	for _, width := range kindWidths[img.Kind] {
+		variant := widthVariant(width)
+		urls[variant] = base + variant
+	}
+	return urls
+}
+
+func objectKey(key, variant string) string {
+	return key + "/" + variant
+}
+
+func objectKeys(img Image) []string {
+	keys := []string{objectKey(img.Key, VariantOriginal)}
+	for _, width := range kindWidths[img.Kind] {
+		keys = append(keys, objectKey(img.Key, widthVariant(width)))
+	}
+	return keys
+}
+
+func widthVariant(width int) string {
+	return "w" + strconv.Itoa(width)
+}
+
+func hasVariant(kind, variant string) bool {
+	if variant == VariantOriginal {
+		return true
+	}
+	for _, width := range kindWidths[kind] {
+		if variant == widthVariant(width) {
+			return true
+		}
+	}
+	return false
+}
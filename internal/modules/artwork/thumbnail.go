@@ -0,0 +1,81 @@
+package artwork
+
+import (
+	"image"
+	"image/color"
+)
+
+// thumbnail scales src down to width, keeping the aspect ratio, and flattens
+// transparency onto white so the result can be stored as JPEG. Every source
+// pixel is averaged into the output pixel it falls on, which is a box filter
+// and gives smooth results for the large reductions thumbnails need. Images
+// narrower than width keep their size.
+func thumbnail(src image.Image, width int) *image.RGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+
+	dw, dh := sw, sh
+	if sw > width {
+		dw = width
+		dh = max(1, int(int64(sh)*int64(width)/int64(sw)))
+	}
+
+	sums := make([]uint64, dw*dh*4)
+	counts := make([]uint32, dw*dh)
+
+	pixel := pixelReader(src)
+	for y := 0; y < sh; y++ {
+		row := (y * dh / sh) * dw
+		for x := 0; x < sw; x++ {
+			r, g, b, a := pixel(bounds.Min.X+x, bounds.Min.Y+y)
+			i := row + x*dw/sw
+			sums[i*4] += uint64(r)
+			sums[i*4+1] += uint64(g)
+			sums[i*4+2] += uint64(b)
+			sums[i*4+3] += uint64(a)
+			counts[i]++
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for i, n := range counts {
+		if n == 0 {
+			continue
+		}
+		count := uint64(n)
+		// Premultiplied colors over white: c + (1 - alpha).
+		white := 0xffff - sums[i*4+3]/count
+		dst.Pix[i*4] = uint8((sums[i*4]/count + white) >> 8)
+		dst.Pix[i*4+1] = uint8((sums[i*4+1]/count + white) >> 8)
+		dst.Pix[i*4+2] = uint8((sums[i*4+2]/count + white) >> 8)
+		dst.Pix[i*4+3] = 0xff
+	}
+	return dst
+}
+
+// pixelReader returns the premultiplied 16-bit color of a pixel, avoiding the
+// interface allocations of image.At for the types the decoders produce.
+func pixelReader(src image.Image) func(x, y int) (r, g, b, a uint32) {
+	switch img := src.(type) {
+	case *image.YCbCr:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return img.YCbCrAt(x, y).RGBA()
+		}
+	case *image.NRGBA:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return img.NRGBAAt(x, y).RGBA()
+		}
+	case *image.RGBA:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return img.RGBAAt(x, y).RGBA()
+		}
+	case *image.Gray:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return img.GrayAt(x, y).RGBA()
+		}
+	default:
+		return func(x, y int) (uint32, uint32, uint32, uint32) {
+			return color.RGBAModel.Convert(src.At(x, y)).RGBA()
+		}
+	}
+}
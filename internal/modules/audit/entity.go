@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	EntityMovie = "movie"
+	EntityUser  = "user"
+)
+
+const (
+	ActionCreate             = "create"
+	ActionUpdate             = "update"
+	ActionDelete             = "delete"
+	ActionRestore            = "restore"
+	ActionPurge              = "purge"
+	ActionVerifyEmail        = "verify_email"
+	ActionChangePassword     = "change_password"
+	ActionResetPassword      = "reset_password"
+	ActionRequestEmailChange = "request_email_change"
+	ActionChangeEmail        = "change_email"
+	ActionAssignRole         = "assign_role"
+	ActionUnlock             = "unlock"
+)
+
+// Actor is who made a change and through which request. A zero UserID stands
+// for an anonymous caller or, without a request ID, the system itself.
+type Actor struct {
+	UserID    uint
+	RequestID string
+	IP        string
+}
+
+// System is the actor of changes made by the app on its own, like purging
+// expired movies.
+var System = Actor{}
+
+// Entry is one change. Before and After hold the fields that changed, or the
+// whole entity when it was created or removed. Entries are never updated or
+// deleted.
+type Entry struct {
+	ID         uint      `gorm:"primaryKey"`
+	ActorID    *uint     `gorm:"index"`
+	Action     string    `gorm:"type:varchar(32);not null;index"`
+	EntityType string    `gorm:"type:varchar(32);not null;index:idx_audit_entries_entity"`
+	EntityID   uint      `gorm:"not null;index:idx_audit_entries_entity"`
+	Before     JSON      `gorm:"type:jsonb"`
+	After      JSON      `gorm:"type:jsonb"`
+	RequestID  string    `gorm:"type:varchar(64);not null;default:'';index"`
+	IP         string    `gorm:"type:varchar(64);not null;default:''"`
+	CreatedAt  time.Time `gorm:"not null;index"`
+}
+
+func (Entry) TableName() string {
+	return "audit_entries"
+}
+
+// JSON is a jsonb column holding raw JSON, or NULL when empty.
+type JSON []byte
+
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return errors.Errorf("cannot scan %T into audit.JSON", value)
+	}
+	return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+type EntryResponse struct {
+	ID         uint      `json:"id"`
+	ActorID    *uint     `json:"actor_id"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   uint      `json:"entity_id"`
+	Before     JSON      `json:"before" swaggertype:"object"`
+	After      JSON      `json:"after" swaggertype:"object"`
+	RequestID  string    `json:"request_id,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Filter narrows GET /admin/audit; each set field must match.
+type Filter struct {
+	ActorID    *uint      `form:"actor_id"`
+	Action     string     `form:"action"`
+	EntityType string     `form:"entity_type"`
+	EntityID   uint       `form:"entity_id"`
+	RequestID  string     `form:"request_id"`
+	From       *time.Time `form:"from"`
+	To         *time.Time `form:"to"`
+
+	Page  int64 `form:"-"`
+	Limit int64 `form:"-"`
+}
+
+type ListResponse struct {
+	Entries []EntryResponse `json:"entries"`
+	Total   uint64          `json:"total"`
+}
+
+// diff reduces before and after to the top level fields that differ. Either
+// may be nil for entities that were created or removed.
+func diff(before, after interface{}) (JSON, JSON, error) {
+	b, err := toFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := toFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if b != nil && a != nil {
+		for key, value := range b {
+			if other, ok := a[key]; ok && string(other) == string(value) {
+				delete(b, key)
+				delete(a, key)
+			}
+		}
+	}
+
+	bj, err := fromFields(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	aj, err := fromFields(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bj, aj, nil
+}
+
+func toFields(v interface{}) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func fromFields(fields map[string]json.RawMessage) (JSON, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,12 @@
+package audit
+
+import "go.uber.org/fx"
+
+var Module = fx.Module(
+	"audit_module",
+	fx.Provide(
+		NewRepository,
+		NewService,
+		NewHandler,
+	),
+)
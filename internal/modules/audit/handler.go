@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	List(c *gin.Context)
+	MovieHistory(c *gin.Context)
+	UserHistory(c *gin.Context)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary List audit entries
+// @Description List recorded changes, newest first, narrowed by any of the filters
+// @Tags admin
+// @Produce json
+// @Param actor_id query int false "User who made the change"
+// @Param action query string false "Action, such as create, update or assign_role"
+// @Param entity_type query string false "Entity type" Enums(movie, user)
+// @Param entity_id query int false "Entity ID"
+// @Param request_id query string false "Request ID"
+// @Param from query string false "Changed at or after, RFC 3339"
+// @Param to query string false "Changed before, RFC 3339"
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(20)
+// @Success 200 {object} common.ResponseWithList{data=[]audit.EntryResponse}
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/audit [get]
+func (h *handler) List(c *gin.Context) {
+	var filter Filter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	h.list(c, filter)
+}
+
+// @Summary Get the history of a movie
+// @Description List the recorded changes of a movie, newest first
+// @Tags movies
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(20)
+// @Success 200 {object} common.ResponseWithList{data=[]audit.EntryResponse}
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/history [get]
+func (h *handler) MovieHistory(c *gin.Context) {
+	h.history(c, EntityMovie)
+}
+
+// @Summary Get the history of a user
+// @Description List the recorded changes of a user account, newest first
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(20)
+// @Success 200 {object} common.ResponseWithList{data=[]audit.EntryResponse}
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/history [get]
+func (h *handler) UserHistory(c *gin.Context) {
+	h.history(c, EntityUser)
+}
+
+func (h *handler) history(c *gin.Context, entityType string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid id",
+			},
+		)
+		return
+	}
+
+	h.list(c, Filter{EntityType: entityType, EntityID: uint(id)})
+}
+
+func (h *handler) list(c *gin.Context, filter Filter) {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	filter.Page = page
+	filter.Limit = limit
+
+	entries, err := h.service.List(filter)
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseWithList{
+			Status:  http.StatusOK,
+			Message: "Audit entries fetched successfully",
+			Data:    entries.Entries,
+			Total:   entries.Total,
+		},
+	)
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(tx *gorm.DB, entry Entry) error
+	List(filter Filter) (*ListResponse, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(psql postgres.PostgresDB) Repository {
+	return &repository{db: psql.DB()}
+}
+
+// Create inserts the entry within tx, the transaction of the change it
+// records.
+func (r *repository) Create(tx *gorm.DB, entry Entry) error {
+	return tx.Create(&entry).Error
+}
+
+func (r *repository) List(filter Filter) (*ListResponse, error) {
+	query := r.db.Model(&Entry{})
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if err := query.Order("created_at DESC, id DESC").
+		Limit(int(filter.Limit)).
+		Offset(int((filter.Page - 1) * filter.Limit)).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	response := ListResponse{
+		Entries: make([]EntryResponse, len(entries)),
+		Total:   uint64(total),
+	}
+	for i, entry := range entries {
+		response.Entries[i] = EntryResponse{
+			ID:         entry.ID,
+			ActorID:    entry.ActorID,
+			Action:     entry.Action,
+			EntityType: entry.EntityType,
+			EntityID:   entry.EntityID,
+			Before:     entry.Before,
+			After:      entry.After,
+			RequestID:  entry.RequestID,
+			IP:         entry.IP,
+			CreatedAt:  entry.CreatedAt,
+		}
+	}
+	return &response, nil
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	Record(tx *gorm.DB, actor Actor, action, entityType string, entityID uint, before, after interface{}) error
+	List(filter Filter) (*ListResponse, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+// ActorFrom reads the actor of a request: the user signed in with the JWT,
+// if any, the request ID and the client IP.
+func ActorFrom(c *gin.Context) Actor {
+	return Actor{
+		UserID:    c.GetUint("user_id"),
+		RequestID: c.GetString("request_id"),
+		IP:        c.ClientIP(),
+	}
+}
+
+// Record appends an entry for a change within tx, the transaction that makes
+// the change, so that the change and its entry are committed together or not
+// at all. before and after are snapshots of the entity, marshaled to JSON
+// objects, and only the fields that differ are kept; an update that changed
+// nothing is not recorded.
+func (s *service) Record(tx *gorm.DB, actor Actor, action, entityType string, entityID uint, before, after interface{}) error {
+	b, a, err := diff(before, after)
+	if err != nil {
+		return errors.Wrap(err, "failed to diff audit entry")
+	}
+	if before != nil && after != nil && string(b) == "{}" {
+		return nil
+	}
+
+	entry := Entry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     b,
+		After:      a,
+		RequestID:  actor.RequestID,
+		IP:         actor.IP,
+	}
+	if actor.UserID != 0 {
+		entry.ActorID = &actor.UserID
+	}
+
+	if err := s.repo.Create(tx, entry); err != nil {
+		return errors.Wrap(err, "failed to record audit entry")
+	}
+	return nil
+}
+
+func (s *service) List(filter Filter) (*ListResponse, error) {
+	return s.repo.List(filter)
+}
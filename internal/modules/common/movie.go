@@ -0,0 +1,8 @@
+package common
+
+// MovieChecker tells whether a movie exists and is not in the trash. The movie
+// module provides it to the modules that attach data to movies, which cannot
+// import the movie module themselves.
+type MovieChecker interface {
+	MovieExists(id uint) (bool, error)
+}
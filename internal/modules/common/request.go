@@ -1,12 +1,65 @@
 package common
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
 type RequestID struct {
 	ID uint `json:"id" validate:"required,numeric,gte=1"`
 }
 
 type RequestSearch struct {
-	Search string `json:"search"`
-	Page   int64  `json:"page"`
-	Limit  int64  `json:"limit"`
+	Search    string `json:"search"`
+	Highlight bool   `json:"highlight"`
+	Page      int64  `json:"page"`
+	Limit     int64  `json:"limit"`
+}
+
+// RequestRef points at a related entity either by ID or by name. A name that
+// does not exist yet is created.
+type RequestRef struct {
+	ID   uint   `json:"id,omitempty" validate:"required_without=Name"`
+	Name string `json:"name,omitempty" validate:"required_without=ID,omitempty,min=2,max=255"`
 }
 
+type SortField struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc"`
+}
+
+// ParseSort parses "column[:asc|desc],..." into sort fields. Only columns
+// listed in allowed are accepted, so the result is safe to put into SQL.
+func ParseSort(raw string, allowed []string) ([]SortField, error) {
+	fields := make([]SortField, 0)
+	if strings.TrimSpace(raw) == "" {
+		return fields, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		column, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+
+		if !slices.Contains(allowed, column) {
+			return nil, fmt.Errorf("unsupported sort column %q", column)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("duplicate sort column %q", column)
+		}
+		seen[column] = true
+
+		field := SortField{Column: column}
+		switch strings.ToLower(direction) {
+		case "", "asc":
+		case "desc":
+			field.Desc = true
+		default:
+			return nil, fmt.Errorf("unsupported sort direction %q", direction)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,57 @@
+package common
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	allowed := []string{"title", "year", "created_at"}
+
+	tests := []struct {
+		name string
+		raw  string
+		want []SortField
+		err  string
+	}{
+		{name: "empty", raw: "", want: []SortField{}},
+		{name: "blank", raw: "   ", want: []SortField{}},
+		{name: "ascending by default", raw: "title", want: []SortField{{Column: "title"}}},
+		{name: "explicit ascending", raw: "title:asc", want: []SortField{{Column: "title"}}},
+		{name: "descending", raw: "year:desc", want: []SortField{{Column: "year", Desc: true}}},
+		{name: "direction is case insensitive", raw: "year:DESC", want: []SortField{{Column: "year", Desc: true}}},
+		{
+			name: "several fields keep their order",
+			raw:  "year:desc, title ,created_at:asc",
+			want: []SortField{{Column: "year", Desc: true}, {Column: "title"}, {Column: "created_at"}},
+		},
+		{name: "unknown column", raw: "budget", err: `unsupported sort column "budget"`},
+		{name: "column case matters", raw: "Title", err: `unsupported sort column "Title"`},
+		{name: "minus prefix is not a direction", raw: "-title", err: `unsupported sort column "-title"`},
+		{name: "plus prefix is not a direction", raw: "+title", err: `unsupported sort column "+title"`},
+		{name: "empty field", raw: "title,", err: `unsupported sort column ""`},
+		{name: "duplicate column", raw: "title,title", err: `duplicate sort column "title"`},
+		{name: "duplicate column in another direction", raw: "title:asc,title:desc", err: `duplicate sort column "title"`},
+		{name: "unknown direction", raw: "title:up", err: `unsupported sort direction "up"`},
+		{name: "empty direction after colon", raw: "title:", want: []SortField{{Column: "title"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSort(tt.raw, allowed)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,10 +7,18 @@ type Response struct {
 }
 
 type ResponseWithList struct {
-	Status  uint16        `json:"status"`
-	Message string        `json:"message"`
-	Data    interface{} `json:"data"`
-	Total   uint64        `json:"total"`
+	Status     uint16      `json:"status"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data"`
+	Total      uint64      `json:"total"`
+	Estimated  bool        `json:"total_estimated,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+}
+
+type ResponseRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
 }
 
 type ResponseID struct {
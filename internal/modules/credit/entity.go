@@ -0,0 +1,72 @@
+package credit
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
+	"gorm.io/gorm"
+)
+
+const (
+	RoleActor    = "actor"
+	RoleWriter   = "writer"
+	RoleProducer = "producer"
+	RoleComposer = "composer"
+)
+
+// Credit links a person to a movie in a role. Lower billing orders are listed
+// first; Character is only set for actors.
+type Credit struct {
+	gorm.Model
+	MovieID      uint          `gorm:"not null;index:idx_credits_movie_billing,priority:1"`
+	PersonID     uint          `gorm:"not null;index"`
+	Person       person.Person `gorm:"constraint:OnDelete:RESTRICT"`
+	Role         string        `gorm:"type:varchar(32);not null;index"`
+	Character    string        `gorm:"column:character_name;type:varchar(255);not null;default:''"`
+	BillingOrder int           `gorm:"not null;index:idx_credits_movie_billing,priority:2"`
+}
+
+type CreditResponse struct {
+	ID           uint               `json:"id"`
+	MovieID      uint               `json:"movie_id"`
+	Person       common.ResponseRef `json:"person"`
+	Role         string             `json:"role"`
+	Character    string             `json:"character,omitempty"`
+	BillingOrder int                `json:"billing_order"`
+}
+
+type FilmographyResponse struct {
+	ID           uint               `json:"id"`
+	Movie        common.ResponseRef `json:"movie"`
+	Year         int                `json:"year"`
+	Role         string             `json:"role"`
+	Character    string             `json:"character,omitempty"`
+	BillingOrder int                `json:"billing_order"`
+}
+
+// CreditInput takes the person by ID or by name. Without a billing order the
+// credit is appended after the existing ones of the movie.
+type CreditInput struct {
+	ID           uint              `json:"-"`
+	MovieID      uint              `json:"-"`
+	Person       common.RequestRef `json:"person"`
+	Role         string            `json:"role" validate:"required,oneof=actor writer producer composer"`
+	Character    string            `json:"character,omitempty" validate:"excluded_unless=Role actor,max=255"`
+	BillingOrder int               `json:"billing_order,omitempty" validate:"omitempty,min=1"`
+}
+
+type CreditFilter struct {
+	ID    uint
+	Role  string `validate:"omitempty,oneof=actor writer producer composer"`
+	Page  int64
+	Limit int64
+}
+
+type CreditListResponse struct {
+	Credits []CreditResponse `json:"credits"`
+	Total   uint64           `json:"total"`
+}
+
+type FilmographyListResponse struct {
+	Credits []FilmographyResponse `json:"credits"`
+	Total   uint64                `json:"total"`
+}
@@ -0,0 +1,12 @@
+package credit
+
+import "go.uber.org/fx"
+
+var Module = fx.Module(
+	"credit_module",
+	fx.Provide(
+		NewRepository,
+		NewService,
+		NewHandler,
+	),
+)
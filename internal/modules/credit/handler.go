@@ -0,0 +1,401 @@
+package credit
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Handler interface {
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+	ListByMovie(c *gin.Context)
+	ListByPerson(c *gin.Context)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Add a credit to a movie
+// @Description Link a person, by ID or by name, to a movie in a role
+// @Tags credits
+// @Accept json
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param credit body CreditInput true "Credit"
+// @Success 201 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/credits [post]
+func (h *handler) Create(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	var req CreditInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid request",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.MovieID = uint(movieID)
+	credit, err := h.service.Create(req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	c.JSON(
+		http.StatusCreated,
+		common.ResponseID{
+			Status:  http.StatusCreated,
+			Message: "Credit created successfully",
+			ID:      credit.ID,
+		},
+	)
+}
+
+// @Summary Update a credit of a movie
+// @Description Update a credit of a movie, billing order is kept when omitted
+// @Tags credits
+// @Accept json
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param credit_id path string true "Credit ID"
+// @Param credit body CreditInput true "Credit"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/credits/{credit_id} [put]
+func (h *handler) Update(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	creditID, err := strconv.ParseUint(c.Param("credit_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid credit id",
+			},
+		)
+		return
+	}
+
+	var req CreditInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.ID = uint(creditID)
+	req.MovieID = uint(movieID)
+	credit, err := h.service.Update(req)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Credit updated successfully",
+			ID:      credit.ID,
+		},
+	)
+}
+
+// @Summary Delete a credit of a movie
+// @Description Delete a credit of a movie
+// @Tags credits
+// @Accept json
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param credit_id path string true "Credit ID"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/credits/{credit_id} [delete]
+func (h *handler) Delete(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	creditID, err := strconv.ParseUint(c.Param("credit_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid credit id",
+			},
+		)
+		return
+	}
+
+	credit, err := h.service.Delete(uint(movieID), uint(creditID))
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Credit deleted successfully",
+			ID:      credit.ID,
+		},
+	)
+}
+
+// @Summary Get the credits of a movie
+// @Description Get the cast and crew of a movie in billing order
+// @Tags credits
+// @Accept json
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param role query string false "Role" Enums(actor, writer, producer, composer)
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(50)
+// @Success 200 {object} common.ResponseWithList
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/movies/{id}/credits [get]
+func (h *handler) ListByMovie(c *gin.Context) {
+	filter, err := parseCreditFilter(c)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	credits, err := h.service.ListByMovie(filter)
+	if err != nil {
+		h.error(c, err)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseWithList{
+			Status:  http.StatusOK,
+			Message: "Credits fetched successfully",
+			Total:   credits.Total,
+			Data:    credits.Credits,
+		},
+	)
+}
+
+// @Summary Get the filmography of a person
+// @Description Get the credits of a person on movies, newest first
+// @Tags credits
+// @Accept json
+// @Produce json
+// @Param id path string true "Person ID"
+// @Param role query string false "Role" Enums(actor, writer, producer, composer)
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(50)
+// @Success 200 {object} common.ResponseWithList
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/people/{id}/filmography [get]
+func (h *handler) ListByPerson(c *gin.Context) {
+	filter, err := parseCreditFilter(c)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	credits, err := h.service.ListByPerson(filter)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Person not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseWithList{
+			Status:  http.StatusOK,
+			Message: "Filmography fetched successfully",
+			Total:   credits.Total,
+			Data:    credits.Credits,
+		},
+	)
+}
+
+func parseCreditFilter(c *gin.Context) (CreditFilter, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return CreditFilter{}, errors.New("invalid id")
+	}
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+
+	filter := CreditFilter{
+		ID:    uint(id),
+		Role:  c.Query("role"),
+		Page:  page,
+		Limit: limit,
+	}
+	if err := common.Validate.Struct(filter); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func (h *handler) error(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrMovieNotFound):
+		c.JSON(
+			http.StatusNotFound,
+			common.ResponseError{
+				Status:  http.StatusNotFound,
+				Message: "Movie not found",
+			},
+		)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(
+			http.StatusNotFound,
+			common.ResponseError{
+				Status:  http.StatusNotFound,
+				Message: "Credit not found",
+			},
+		)
+	case errors.Is(err, person.ErrNotFound):
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+	default:
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+	}
+}
@@ -0,0 +1,193 @@
+package credit
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(req Credit) (*common.ResponseID, error)
+	Update(req Credit) (*common.ResponseID, error)
+	Delete(movieID, id uint) (*common.ResponseID, error)
+	ListByMovie(filter CreditFilter) (*CreditListResponse, error)
+	ListByPerson(filter CreditFilter) (*FilmographyListResponse, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(psql postgres.PostgresDB) Repository {
+	return &repository{db: psql.DB()}
+}
+
+// Create stores the credit. Without a billing order it is placed after the
+// last credit of the movie.
+func (r *repository) Create(req Credit) (*common.ResponseID, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if req.BillingOrder == 0 {
+			if err := tx.Raw(`
+				SELECT COALESCE(MAX(billing_order), 0) + 1
+				FROM credits
+				WHERE movie_id = ? AND deleted_at IS NULL
+			`, req.MovieID).Scan(&req.BillingOrder).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Omit("Person").Create(&req).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+func (r *repository) Update(req Credit) (*common.ResponseID, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if req.BillingOrder == 0 {
+			if err := tx.Model(&Credit{}).
+				Where("id = ? AND movie_id = ?", req.ID, req.MovieID).
+				Pluck("billing_order", &req.BillingOrder).Error; err != nil {
+				return err
+			}
+		}
+
+		res := tx.Model(&Credit{}).
+			Where("id = ? AND movie_id = ?", req.ID, req.MovieID).
+			Select("person_id", "role", "character_name", "billing_order").
+			Updates(req)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+func (r *repository) Delete(movieID, id uint) (*common.ResponseID, error) {
+	res := r.db.Where("movie_id = ?", movieID).Delete(&Credit{}, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &common.ResponseID{ID: id}, nil
+}
+
+func (r *repository) ListByMovie(filter CreditFilter) (*CreditListResponse, error) {
+	response := CreditListResponse{
+		Credits: make([]CreditResponse, 0),
+	}
+
+	where := `c.movie_id = ? AND c.deleted_at IS NULL`
+	args := []interface{}{filter.ID}
+	if filter.Role != "" {
+		where += ` AND c.role = ?`
+		args = append(args, filter.Role)
+	}
+
+	var total int64
+	if err := r.db.Raw(`SELECT COUNT(*) FROM credits c WHERE `+where, args...).Scan(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID           uint
+		MovieID      uint
+		PersonID     uint
+		PersonName   string
+		Role         string
+		Character    string
+		BillingOrder int
+	}
+	if err := r.db.Raw(`
+		SELECT c.id, c.movie_id, c.person_id, p.name AS person_name, c.role, c.character_name AS character, c.billing_order
+		FROM credits c
+		JOIN people p ON p.id = c.person_id
+		WHERE `+where+`
+		ORDER BY c.billing_order ASC, c.id ASC
+		LIMIT ? OFFSET ?
+	`, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		response.Credits = append(response.Credits, CreditResponse{
+			ID:           row.ID,
+			MovieID:      row.MovieID,
+			Person:       common.ResponseRef{ID: row.PersonID, Name: row.PersonName},
+			Role:         row.Role,
+			Character:    row.Character,
+			BillingOrder: row.BillingOrder,
+		})
+	}
+
+	response.Total = uint64(total)
+	return &response, nil
+}
+
+// ListByPerson lists the credits of a person on live movies, newest first.
+func (r *repository) ListByPerson(filter CreditFilter) (*FilmographyListResponse, error) {
+	response := FilmographyListResponse{
+		Credits: make([]FilmographyResponse, 0),
+	}
+
+	where := `c.person_id = ? AND c.deleted_at IS NULL`
+	args := []interface{}{filter.ID}
+	if filter.Role != "" {
+		where += ` AND c.role = ?`
+		args = append(args, filter.Role)
+	}
+
+	var total int64
+	if err := r.db.Raw(`
+		SELECT COUNT(*)
+		FROM credits c
+		JOIN movies m ON m.id = c.movie_id AND m.deleted_at IS NULL
+		WHERE `+where, args...).Scan(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID           uint
+		MovieID      uint
+		Title        string
+		Year         int
+		Role         string
+		Character    string
+		BillingOrder int
+	}
+	if err := r.db.Raw(`
+		SELECT c.id, c.movie_id, m.title, m.year, c.role, c.character_name AS character, c.billing_order
+		FROM credits c
+		JOIN movies m ON m.id = c.movie_id AND m.deleted_at IS NULL
+		WHERE `+where+`
+		ORDER BY m.year DESC, m.title ASC, c.billing_order ASC, c.id ASC
+		LIMIT ? OFFSET ?
+	`, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		response.Credits = append(response.Credits, FilmographyResponse{
+			ID:           row.ID,
+			Movie:        common.ResponseRef{ID: row.MovieID, Name: row.Title},
+			Year:         row.Year,
+			Role:         row.Role,
+			Character:    row.Character,
+			BillingOrder: row.BillingOrder,
+		})
+	}
+
+	response.Total = uint64(total)
+	return &response, nil
+}
@@ -0,0 +1,100 @@
+package credit
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	Create(req CreditInput) (*common.ResponseID, error)
+	Update(req CreditInput) (*common.ResponseID, error)
+	Delete(movieID, id uint) (*common.ResponseID, error)
+	ListByMovie(filter CreditFilter) (*CreditListResponse, error)
+	ListByPerson(filter CreditFilter) (*FilmographyListResponse, error)
+	TopCast(movieID uint, limit int64) ([]CreditResponse, error)
+}
+
+var ErrMovieNotFound = errors.New("movie not found")
+
+type service struct {
+	repo   Repository
+	people person.Service
+	movies common.MovieChecker
+}
+
+func NewService(repo Repository, people person.Service, movies common.MovieChecker) Service {
+	return &service{repo: repo, people: people, movies: movies}
+}
+
+func (s *service) Create(req CreditInput) (*common.ResponseID, error) {
+	credit, err := s.credit(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Create(*credit)
+}
+
+func (s *service) Update(req CreditInput) (*common.ResponseID, error) {
+	credit, err := s.credit(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Update(*credit)
+}
+
+func (s *service) credit(req CreditInput) (*Credit, error) {
+	exists, err := s.movies.MovieExists(req.MovieID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrMovieNotFound
+	}
+
+	people, err := s.people.Resolve([]common.RequestRef{req.Person})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credit{
+		Model:        gorm.Model{ID: req.ID},
+		MovieID:      req.MovieID,
+		PersonID:     people[0].ID,
+		Role:         req.Role,
+		Character:    req.Character,
+		BillingOrder: req.BillingOrder,
+	}, nil
+}
+
+func (s *service) Delete(movieID, id uint) (*common.ResponseID, error) {
+	return s.repo.Delete(movieID, id)
+}
+
+func (s *service) ListByMovie(filter CreditFilter) (*CreditListResponse, error) {
+	exists, err := s.movies.MovieExists(filter.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrMovieNotFound
+	}
+	return s.repo.ListByMovie(filter)
+}
+
+func (s *service) ListByPerson(filter CreditFilter) (*FilmographyListResponse, error) {
+	if _, err := s.people.GetByID(common.RequestID{ID: filter.ID}); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByPerson(filter)
+}
+
+// TopCast returns the first billed actors of a movie.
+func (s *service) TopCast(movieID uint, limit int64) ([]CreditResponse, error) {
+	res, err := s.repo.ListByMovie(CreditFilter{ID: movieID, Role: RoleActor, Page: 1, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return res.Credits, nil
+}
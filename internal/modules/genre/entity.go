@@ -0,0 +1,29 @@
+package genre
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Genre struct {
+	gorm.Model
+	Name string `gorm:"type:varchar(255);not null;uniqueIndex:idx_genres_name,where:deleted_at IS NULL"`
+}
+
+type GenreResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type GenreInput struct {
+	ID   uint   `json:"-"`
+	Name string `json:"name" validate:"required,min=2,max=255"`
+}
+
+type GenreListResponse struct {
+	Genres []GenreResponse `json:"genres"`
+	Total  uint64          `json:"total"`
+}
@@ -0,0 +1,12 @@
+package genre
+
+import "go.uber.org/fx"
+
+var Module = fx.Module(
+	"genre_module",
+	fx.Provide(
+		NewRepository,
+		NewService,
+		NewHandler,
+	),
+)
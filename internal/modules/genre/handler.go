@@ -0,0 +1,369 @@
+package genre
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Handler interface {
+	Create(c *gin.Context)
+	GetByID(c *gin.Context)
+	GetAll(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Create a new genre
+// @Description Create a new genre, names are stored lowercase
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param genre body GenreInput true "Genre"
+// @Success 201 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/genres [post]
+func (h *handler) Create(c *gin.Context) {
+	var req GenreInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid request",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	genre, err := h.service.Create(req)
+	if err != nil {
+		if helper.ErrorIs(err, "duplicate") {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Already exists",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusCreated,
+		common.ResponseID{
+			Status:  http.StatusCreated,
+			Message: "Genre created successfully",
+			ID:      genre.ID,
+		},
+	)
+}
+
+// @Summary Get a genre by ID
+// @Description Get a genre by ID
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param id path string true "Genre ID"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/genres/{id} [get]
+func (h *handler) GetByID(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid genre id",
+			},
+		)
+		return
+	}
+
+	genre, err := h.service.GetByID(common.RequestID{ID: uint(idUint)})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Genre not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "Genre fetched successfully",
+			Data:    genre,
+		},
+	)
+}
+
+// @Summary Get all genres
+// @Description Get all genres ordered by name
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(50)
+// @Param search query string false "Name contains"
+// @Success 200 {object} common.ResponseWithList
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/genres [get]
+func (h *handler) GetAll(c *gin.Context) {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+
+	genres, err := h.service.GetAll(common.RequestSearch{
+		Search: strings.TrimSpace(c.Query("search")),
+		Page:   page,
+		Limit:  limit,
+	})
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseWithList{
+			Status:  http.StatusOK,
+			Message: "Genres fetched successfully",
+			Total:   genres.Total,
+			Data:    genres.Genres,
+		},
+	)
+}
+
+// @Summary Rename a genre by ID
+// @Description Rename a genre by ID, linked movies follow the new name
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param id path string true "Genre ID"
+// @Param genre body GenreInput true "Genre"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/genres/{id} [put]
+func (h *handler) Update(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid genre id",
+			},
+		)
+		return
+	}
+
+	var req GenreInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.ID = uint(idUint)
+	genre, err := h.service.Update(req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Genre not found",
+				},
+			)
+			return
+		}
+
+		if helper.ErrorIs(err, "duplicate") {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Already exists",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Genre updated successfully",
+			ID:      genre.ID,
+		},
+	)
+}
+
+// @Summary Delete a genre by ID
+// @Description Delete a genre by ID, genres still linked to movies cannot be deleted
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param id path string true "Genre ID"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 409 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/genres/{id} [delete]
+func (h *handler) Delete(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid genre id",
+			},
+		)
+		return
+	}
+
+	genre, err := h.service.Delete(common.RequestID{ID: uint(idUint)})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Genre not found",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrInUse) {
+			c.JSON(
+				http.StatusConflict,
+				common.ResponseError{
+					Status:  http.StatusConflict,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Genre deleted successfully",
+			ID:      genre.ID,
+		},
+	)
+}
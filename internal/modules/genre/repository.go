@@ -0,0 +1,142 @@
+package genre
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	Create(req Genre) (*common.ResponseID, error)
+	GetByID(id uint) (*Genre, error)
+	GetAll(req common.RequestSearch) (*GenreListResponse, error)
+	Update(req Genre) (*common.ResponseID, error)
+	Delete(id uint) (*common.ResponseID, error)
+	FirstOrCreate(name string) (*Genre, error)
+	CountMovies(id uint) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(psql postgres.PostgresDB) Repository {
+	return &repository{db: psql.DB()}
+}
+
+func (r *repository) Create(req Genre) (*common.ResponseID, error) {
+	if err := r.db.Create(&req).Error; err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+func (r *repository) GetByID(id uint) (*Genre, error) {
+	var genre Genre
+	if err := r.db.First(&genre, id).Error; err != nil {
+		return nil, err
+	}
+	return &genre, nil
+}
+
+func (r *repository) GetAll(req common.RequestSearch) (*GenreListResponse, error) {
+	response := GenreListResponse{
+		Genres: make([]GenreResponse, 0),
+	}
+
+	query := r.db.Model(&Genre{})
+	if req.Search != "" {
+		query = query.Where("name ILIKE ?", "%"+req.Search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if err := query.Order("name ASC").
+		Limit(int(req.Limit)).
+		Offset(int((req.Page - 1) * req.Limit)).
+		Scan(&response.Genres).Error; err != nil {
+		return nil, err
+	}
+
+	response.Total = uint64(total)
+	return &response, nil
+}
+
+// Update renames the genre and rewrites the denormalized genre names of the
+// movies linked to it, which search and sorting read.
+func (r *repository) Update(req Genre) (*common.ResponseID, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Genre{}).Where("id = ?", req.ID).Update("name", req.Name)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Exec(`
+			UPDATE movies AS m
+			SET genre = COALESCE((
+				SELECT string_agg(g.name, ', ' ORDER BY g.name)
+				FROM movie_genres mg
+				JOIN genres g ON g.id = mg.genre_id AND g.deleted_at IS NULL
+				WHERE mg.movie_id = m.id
+			), '')
+			WHERE m.id IN (SELECT movie_id FROM movie_genres WHERE genre_id = ?)
+		`, req.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+func (r *repository) Delete(id uint) (*common.ResponseID, error) {
+	res := r.db.Delete(&Genre{}, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &common.ResponseID{ID: id}, nil
+}
+
+// FirstOrCreate returns the live genre with the given name, creating it when
+// it does not exist yet. Concurrent callers end up with the same row.
+func (r *repository) FirstOrCreate(name string) (*Genre, error) {
+	genre := Genre{Name: name}
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "name"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+		DoNothing:   true,
+	}).Create(&genre).Error; err != nil {
+		return nil, err
+	}
+
+	if genre.ID != 0 {
+		return &genre, nil
+	}
+
+	if err := r.db.Where("name = ?", name).First(&genre).Error; err != nil {
+		return nil, err
+	}
+	return &genre, nil
+}
+
+func (r *repository) CountMovies(id uint) (int64, error) {
+	var count int64
+	if err := r.db.Raw(`
+		SELECT COUNT(*)
+		FROM movie_genres mg
+		JOIN movies m ON m.id = mg.movie_id AND m.deleted_at IS NULL
+		WHERE mg.genre_id = ?
+	`, id).Scan(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
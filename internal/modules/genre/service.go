@@ -0,0 +1,123 @@
+package genre
+
+import (
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	Create(req GenreInput) (*common.ResponseID, error)
+	GetByID(req common.RequestID) (*GenreResponse, error)
+	GetAll(req common.RequestSearch) (*GenreListResponse, error)
+	Update(req GenreInput) (*common.ResponseID, error)
+	Delete(req common.RequestID) (*common.ResponseID, error)
+	Resolve(refs []common.RequestRef) ([]Genre, error)
+	Check(refs []common.RequestRef) error
+}
+
+var (
+	ErrInUse    = errors.New("genre is used by movies")
+	ErrNotFound = errors.New("genre not found")
+)
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+// NormalizeName lowercases the name and collapses its whitespace, so that
+// "Science  Fiction" and "science fiction" are the same genre.
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func (s *service) Create(req GenreInput) (*common.ResponseID, error) {
+	return s.repo.Create(Genre{Name: NormalizeName(req.Name)})
+}
+
+func (s *service) GetByID(req common.RequestID) (*GenreResponse, error) {
+	genre, err := s.repo.GetByID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &GenreResponse{
+		ID:        genre.ID,
+		Name:      genre.Name,
+		CreatedAt: genre.CreatedAt,
+		UpdatedAt: genre.UpdatedAt,
+	}, nil
+}
+
+func (s *service) GetAll(req common.RequestSearch) (*GenreListResponse, error) {
+	return s.repo.GetAll(req)
+}
+
+func (s *service) Update(req GenreInput) (*common.ResponseID, error) {
+	return s.repo.Update(Genre{Model: gorm.Model{ID: req.ID}, Name: NormalizeName(req.Name)})
+}
+
+func (s *service) Delete(req common.RequestID) (*common.ResponseID, error) {
+	count, err := s.repo.CountMovies(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrInUse
+	}
+	return s.repo.Delete(req.ID)
+}
+
+// Resolve turns references into genres. References by ID must exist, names
+// are created on first use. Duplicates are dropped.
+func (s *service) Resolve(refs []common.RequestRef) ([]Genre, error) {
+	genres := make([]Genre, 0, len(refs))
+	seen := make(map[uint]bool, len(refs))
+
+	for _, ref := range refs {
+		var (
+			genre *Genre
+			err   error
+		)
+		if ref.ID != 0 {
+			genre, err = s.repo.GetByID(ref.ID)
+			if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.Wrapf(ErrNotFound, "genre %d", ref.ID)
+			}
+		} else {
+			genre, err = s.repo.FirstOrCreate(NormalizeName(ref.Name))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[genre.ID] {
+			seen[genre.ID] = true
+			genres = append(genres, *genre)
+		}
+	}
+
+	return genres, nil
+}
+
+// Check fails like Resolve on references by ID that do not exist, but creates
+// nothing.
+func (s *service) Check(refs []common.RequestRef) error {
+	for _, ref := range refs {
+		if ref.ID == 0 {
+			continue
+		}
+		if _, err := s.repo.GetByID(ref.ID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.Wrapf(ErrNotFound, "genre %d", ref.ID)
+			}
+			return err
+		}
+	}
+	return nil
+}
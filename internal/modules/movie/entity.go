@@ -3,47 +3,216 @@ package movie
 import (
 	"time"
 
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/artwork"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/credit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/genre"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
 	"gorm.io/gorm"
 )
 
+// Movie links to its genres and directors. Genre and Director hold their
+// names, comma separated, and are kept in sync by the repository for search
+// and sorting; they are not written directly. Likewise Rating and RatingCount
+// mirror the user ratings aggregate maintained by the rating module.
+// Version counts the edits of the movie and backs its ETag; rating changes
+// do not bump it. Titles are unique among live movies only, so a deleted
+// movie does not block creating another with its title.
 type Movie struct {
 	gorm.Model
-	Title     string         `gorm:"type:varchar(255);not null;unique;index"`
-	Year      int            `gorm:"type:int;not null;index"`
-	Genre     string         `gorm:"type:varchar(255);not null;index"`
-	Rating    float64        `gorm:"type:float;not null;index"`
-	Director  string         `gorm:"type:varchar(255);not null;index"`
+	Title       string  `gorm:"type:varchar(255);not null;index;uniqueIndex:idx_movies_title_live,where:deleted_at IS NULL"`
+	Year        int     `gorm:"type:int;not null;index"`
+	Genre       string  `gorm:"type:varchar(255);not null;index"`
+	Rating      float64 `gorm:"type:float;not null;index"`
+	RatingCount int64   `gorm:"not null;default:0;index"`
+	Director    string  `gorm:"type:varchar(255);not null;index"`
+	Version     int64   `gorm:"not null;default:1"`
+
+	Genres    []genre.Genre   `gorm:"many2many:movie_genres"`
+	Directors []person.Person `gorm:"many2many:movie_directors"`
+}
+
+// MovieSnapshot is what the audit log keeps of a movie.
+type MovieSnapshot struct {
+	ID       uint   `json:"-"`
+	Title    string `json:"title"`
+	Year     int    `json:"year"`
+	Genre    string `json:"genre"`
+	Director string `json:"director"`
 }
 
 type MovieResponse struct {
-	ID        uint      `json:"id"`
-	Title     string    `json:"title"`
-	Year      int       `json:"year"`
-	Genre     string    `json:"genre"`
-	Rating    float64   `json:"rating"`
-	Director  string    `json:"director"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Year        int       `json:"year"`
+	Genre       string    `json:"genre"`
+	Rating      float64   `json:"rating"`
+	RatingCount int64     `json:"rating_count"`
+	Director    string    `json:"director"`
+	Version     int64     `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	RatingHistogram []int64 `json:"rating_histogram,omitempty" gorm:"-"`
+
+	Genres    []common.ResponseRef `json:"genres" gorm:"-"`
+	Directors []common.ResponseRef `json:"directors" gorm:"-"`
+
+	Cast []credit.CreditResponse `json:"cast,omitempty" gorm:"-"`
+
+	InWatchlist *bool `json:"in_watchlist,omitempty" gorm:"-"`
+
+	Images *artwork.MovieImages `json:"images,omitempty" gorm:"-"`
+
+	Relevance *float64 `json:"relevance,omitempty"`
+	Snippet   string   `json:"snippet,omitempty"`
 }
 
+// MovieCreateInput takes genres and directors by ID or by name; unknown names
+// are created. The single genre and director fields are still accepted and
+// count as one more name.
 type MovieCreateInput struct {
-	Title    string  `json:"title" validate:"required,min=2,lowercase"`
-	Year     int     `json:"year" validate:"required,min=1800"`
-	Genre    string  `json:"genre" validate:"required,min=2,lowercase"`
-	Rating   float64 `json:"rating" validate:"required,min=0,max=10"`
-	Director string  `json:"director" validate:"required,min=2,lowercase"`
+	Title     string              `json:"title" validate:"required,min=2,lowercase"`
+	Year      int                 `json:"year" validate:"required,min=1800"`
+	Genre     string              `json:"genre,omitempty" validate:"required_without=Genres,omitempty,min=2,lowercase"`
+	Genres    []common.RequestRef `json:"genres,omitempty" validate:"required_without=Genre,omitempty,max=20,dive"`
+	Director  string              `json:"director,omitempty" validate:"required_without=Directors,omitempty,min=2,lowercase"`
+	Directors []common.RequestRef `json:"directors,omitempty" validate:"required_without=Director,omitempty,max=20,dive"`
 }
 
+// MovieUpdateInput replaces the movie. When IfMatch is set, which the handler
+// takes from the If-Match header, only a movie at one of those versions is
+// updated.
 type MovieUpdateInput struct {
-	ID       uint    `json:"-"`
-	Title    string  `json:"title" validate:"required,min=2,lowercase"`
-	Year     int     `json:"year" validate:"required,min=1800"`
-	Genre    string  `json:"genre" validate:"required,min=2,lowercase"`
-	Rating   float64 `json:"rating" validate:"required,min=0,max=10"`
-	Director string  `json:"director" validate:"required,min=2,lowercase"`
+	ID        uint                `json:"-"`
+	IfMatch   []int64             `json:"-"`
+	Title     string              `json:"title" validate:"required,min=2,lowercase"`
+	Year      int                 `json:"year" validate:"required,min=1800"`
+	Genre     string              `json:"genre,omitempty" validate:"required_without=Genres,omitempty,min=2,lowercase"`
+	Genres    []common.RequestRef `json:"genres,omitempty" validate:"required_without=Genre,omitempty,max=20,dive"`
+	Director  string              `json:"director,omitempty" validate:"required_without=Directors,omitempty,min=2,lowercase"`
+	Directors []common.RequestRef `json:"directors,omitempty" validate:"required_without=Director,omitempty,max=20,dive"`
+}
+
+const (
+	PatchFormatMerge = "application/merge-patch+json"
+	PatchFormatJSON  = "application/json-patch+json"
+)
+
+// MoviePatchInput changes part of a movie. Patch is a JSON Merge Patch or a
+// JSON Patch, as Format says, against the movie in the shape of
+// MovieUpdateInput.
+type MoviePatchInput struct {
+	ID      uint
+	IfMatch []int64
+	Format  string
+	Patch   []byte
+}
+
+// MovieFilter holds the typed list query of GET /movies. Each set field
+// narrows the result and Sort is limited to MovieSortColumns.
+type MovieFilter struct {
+	common.RequestSearch `form:"-"`
+
+	YearFrom    *int       `form:"year_from" validate:"omitempty,min=1800"`
+	YearTo      *int       `form:"year_to" validate:"omitempty,min=1800"`
+	RatingMin   *float64   `form:"rating_min" validate:"omitempty,min=0,max=10"`
+	RatingMax   *float64   `form:"rating_max" validate:"omitempty,min=0,max=10"`
+	Genres      []string   `form:"genre" validate:"omitempty,dive,min=1"`
+	GenreIDs    []uint     `form:"genre_id"`
+	Director    string     `form:"director"`
+	DirectorIDs []uint     `form:"director_id"`
+	CreatedFrom *time.Time `form:"created_from"`
+	CreatedTo   *time.Time `form:"created_to"`
+	UpdatedFrom *time.Time `form:"updated_from"`
+	UpdatedTo   *time.Time `form:"updated_to"`
+
+	Sort []common.SortField `form:"-"`
+
+	CursorMode    bool         `form:"-"`
+	Cursor        *MovieCursor `form:"-"`
+	EstimateCount bool         `form:"-"`
+
+	// ViewerID is the signed-in caller, if any; it enables in_watchlist.
+	ViewerID uint `form:"-"`
+}
+
+// MovieCursor is the decoded form of the opaque next/prev cursors. It pins the
+// sort and the filter it was issued for together with the sort values of the
+// boundary row.
+type MovieCursor struct {
+	Sort     string   `json:"s"`
+	Filter   string   `json:"f"`
+	Values   []string `json:"v"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+var MovieSortColumns = []string{"title", "year", "rating", "rating_count", "director", "genre", "created_at", "updated_at", "relevance"}
+
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+
+	ImportCreated  = "created"
+	ImportUpdated  = "updated"
+	ImportRejected = "rejected"
+)
+
+// ImportRowReport is the outcome of one row of an import. Line is the line
+// of the row in the uploaded file.
+type ImportRowReport struct {
+	Line   int    `json:"line"`
+	Title  string `json:"title,omitempty"`
+	Result string `json:"result"`
+	ID     uint   `json:"id,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun   bool              `json:"dry_run"`
+	Created  int               `json:"created"`
+	Updated  int               `json:"updated"`
+	Rejected int               `json:"rejected"`
+	Rows     []ImportRowReport `json:"rows"`
+}
+
+type importResult struct {
+	ID      uint
+	Created bool
+	Err     error
+}
+
+// MovieVersion is the version a write left the movie at.
+type MovieVersion struct {
+	ID      uint
+	Version int64
+}
+
+// TrashFilter pages through deleted movies.
+type TrashFilter struct {
+	Page  int64
+	Limit int64
+}
+
+// TrashedMovieResponse is a deleted movie. PurgeAt is when the retention job
+// removes it for good, if retention is enabled.
+type TrashedMovieResponse struct {
+	MovieResponse
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty" gorm:"-"`
+}
+
+type TrashListResponse struct {
+	Movies []TrashedMovieResponse `json:"movies"`
+	Total  uint64                 `json:"total"`
 }
 
 type MovieListResponse struct {
-	Movies []MovieResponse `json:"movies"`
-	Total  uint64          `json:"total"`
+	Movies     []MovieResponse `json:"movies"`
+	Total      uint64          `json:"total"`
+	Estimated  bool            `json:"total_estimated,omitempty"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PrevCursor string          `json:"prev_cursor,omitempty"`
+
+	hasMore bool
 }
@@ -0,0 +1,260 @@
+package movie
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/pkgs/xlsx"
+	"github.com/pkg/errors"
+)
+
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatXLSX   = "xlsx"
+)
+
+// ExportColumns are the columns an export can select, in their default order.
+var ExportColumns = []string{"id", "title", "year", "genre", "director", "rating", "rating_count", "created_at", "updated_at"}
+
+var ErrInvalidExport = errors.New("invalid export")
+
+// ParseExportColumns reads a comma separated column list. An empty list
+// selects every column.
+func ParseExportColumns(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return ExportColumns, nil
+	}
+
+	columns := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, column := range strings.Split(raw, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !isExportColumn(column) {
+			return nil, errors.Wrapf(ErrInvalidExport, "unknown column %q", column)
+		}
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+func isExportColumn(column string) bool {
+	for _, c := range ExportColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func exportValue(movie MovieResponse, column string) interface{} {
+	switch column {
+	case "id":
+		return movie.ID
+	case "title":
+		return movie.Title
+	case "year":
+		return movie.Year
+	case "genre":
+		return movie.Genre
+	case "director":
+		return movie.Director
+	case "rating":
+		return movie.Rating
+	case "rating_count":
+		return movie.RatingCount
+	case "created_at":
+		return movie.CreatedAt
+	case "updated_at":
+		return movie.UpdatedAt
+	default:
+		return nil
+	}
+}
+
+// exportEncoder writes exported movies in one format. Nothing is written
+// before the first movie or Close, so a failing query can still be answered
+// with an error response.
+type exportEncoder interface {
+	Encode(movie MovieResponse) error
+	Close() error
+}
+
+func newExportEncoder(w io.Writer, format string, columns []string) (exportEncoder, error) {
+	switch format {
+	case ExportFormatCSV:
+		return &csvEncoder{w: w, columns: columns}, nil
+	case ExportFormatNDJSON:
+		return &ndjsonEncoder{w: w, columns: columns}, nil
+	case ExportFormatXLSX:
+		return &xlsxEncoder{w: w, columns: columns}, nil
+	default:
+		return nil, errors.Wrapf(ErrInvalidExport, "unknown format %q", format)
+	}
+}
+
+type csvEncoder struct {
+	w       io.Writer
+	columns []string
+	cw      *csv.Writer
+	record  []string
+}
+
+func (e *csvEncoder) start() error {
+	if e.cw != nil {
+		return nil
+	}
+	e.cw = csv.NewWriter(e.w)
+	e.record = make([]string, len(e.columns))
+	return e.cw.Write(e.columns)
+}
+
+func (e *csvEncoder) Encode(movie MovieResponse) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	for i, column := range e.columns {
+		switch v := exportValue(movie, column).(type) {
+		case time.Time:
+			e.record[i] = v.UTC().Format(time.RFC3339)
+		case float64:
+			e.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			e.record[i] = csvSafe(v)
+		default:
+			e.record[i] = toString(v)
+		}
+	}
+	return e.cw.Write(e.record)
+}
+
+func (e *csvEncoder) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+// csvSafe keeps spreadsheet apps from running text cells as formulas.
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return ""
+	}
+}
+
+// ndjsonEncoder writes one object per movie with the keys in column order.
+type ndjsonEncoder struct {
+	w       io.Writer
+	columns []string
+	bw      *bufio.Writer
+}
+
+func (e *ndjsonEncoder) Encode(movie MovieResponse) error {
+	if e.bw == nil {
+		e.bw = bufio.NewWriter(e.w)
+	}
+
+	e.bw.WriteByte('{')
+	for i, column := range e.columns {
+		if i > 0 {
+			e.bw.WriteByte(',')
+		}
+		value, err := json.Marshal(exportValue(movie, column))
+		if err != nil {
+			return err
+		}
+		e.bw.WriteString(strconv.Quote(column))
+		e.bw.WriteByte(':')
+		e.bw.Write(value)
+	}
+	e.bw.WriteString("}\n")
+
+	return nil
+}
+
+func (e *ndjsonEncoder) Close() error {
+	if e.bw == nil {
+		return nil
+	}
+	return e.bw.Flush()
+}
+
+type xlsxEncoder struct {
+	w       io.Writer
+	columns []string
+	xw      *xlsx.Writer
+	row     []interface{}
+}
+
+func (e *xlsxEncoder) start() error {
+	if e.xw != nil {
+		return nil
+	}
+
+	xw, err := xlsx.NewWriter(e.w, "movies")
+	if err != nil {
+		return err
+	}
+	e.xw = xw
+	e.row = make([]interface{}, len(e.columns))
+
+	for i, column := range e.columns {
+		e.row[i] = column
+	}
+	return e.xw.WriteRow(e.row...)
+}
+
+func (e *xlsxEncoder) Encode(movie MovieResponse) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	for i, column := range e.columns {
+		e.row[i] = exportValue(movie, column)
+	}
+	return e.xw.WriteRow(e.row...)
+}
+
+func (e *xlsxEncoder) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	return e.xw.Close()
+}
+
+// Export writes every movie matching the filter to w, in the sort of GetAll
+// but without paging. Rows are streamed from the database as they are
+// encoded, so memory use does not depend on the size of the catalogue.
+func (s *service) Export(filter MovieFilter, format string, columns []string, w io.Writer) error {
+	encoder, err := newExportEncoder(w, format, columns)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Export(filter, encoder.Encode); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
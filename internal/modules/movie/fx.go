@@ -6,7 +6,9 @@ var Module = fx.Module(
 	"movie_module",
 	fx.Provide(
 		NewRepository,
+		NewMovieChecker,
 		NewService,
 		NewHandler,
 	),
+	fx.Invoke(runPurgeJob),
 )
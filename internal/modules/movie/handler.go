@@ -1,11 +1,19 @@
 package movie
 
 import (
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/genre"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
 	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/jsonpatch"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
@@ -16,7 +24,13 @@ type Handler interface {
 	GetByID(c *gin.Context)
 	GetAll(c *gin.Context)
 	Update(c *gin.Context)
+	Patch(c *gin.Context)
 	Delete(c *gin.Context)
+	Import(c *gin.Context)
+	Export(c *gin.Context)
+	ListTrash(c *gin.Context)
+	Restore(c *gin.Context)
+	Purge(c *gin.Context)
 }
 
 type handler struct {
@@ -35,6 +49,7 @@ func NewHandler(service Service) Handler {
 // @Param movie body MovieCreateInput true "Movie"
 // @Success 201 {object} common.ResponseID
 // @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
 // @Failure 500 {object} common.ResponseError
 // @Security ApiKeyAuth
 // @Router /api/v1/movies [post]
@@ -62,8 +77,19 @@ func (h *handler) Create(c *gin.Context) {
 		return
 	}
 
-	movie, err := h.service.Create(req)
+	movie, err := h.service.Create(req, audit.ActorFrom(c))
 	if err != nil {
+		if errors.Is(err, genre.ErrNotFound) || errors.Is(err, person.ErrNotFound) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
 		if helper.ErrorIs(err, "duplicate") {
 			c.JSON(
 				http.StatusBadRequest,
@@ -101,7 +127,9 @@ func (h *handler) Create(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Movie ID"
+// @Param include query string false "Embed related data, cast adds the top-billed actors" Enums(cast)
 // @Success 200 {object} common.Response
+// @Header 200 {string} ETag "Version of the movie, for If-Match"
 // @Failure 400 {object} common.ResponseError
 // @Failure 404 {object} common.ResponseError
 // @Failure 500 {object} common.ResponseError
@@ -132,7 +160,25 @@ func (h *handler) GetByID(c *gin.Context) {
 		return
 	}
 
-	movie, err := h.service.GetByID(common.RequestID{ID: uint(idUint)})
+	includeCast := false
+	for _, include := range strings.Split(c.Query("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "":
+		case "cast":
+			includeCast = true
+		default:
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Invalid include",
+				},
+			)
+			return
+		}
+	}
+
+	movie, err := h.service.GetByID(common.RequestID{ID: uint(idUint)}, includeCast)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(
@@ -155,6 +201,7 @@ func (h *handler) GetByID(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", movieETag(movie.Version))
 	c.JSON(
 		http.StatusOK,
 		common.Response{
@@ -172,8 +219,26 @@ func (h *handler) GetByID(c *gin.Context) {
 // @Produce json
 // @Param page query int false "Page" default(1)
 // @Param limit query int false "Limit" default(10)
-// @Param search query string false "Search"
+// @Param search query string false "Full-text search over title, director and genre"
+// @Param highlight query bool false "Return highlighted snippets for search matches"
+// @Param year_from query int false "Minimum year"
+// @Param year_to query int false "Maximum year"
+// @Param rating_min query number false "Minimum rating"
+// @Param rating_max query number false "Maximum rating"
+// @Param genre query []string false "Genres, repeated or comma separated" collectionFormat(multi)
+// @Param genre_id query []int false "Genre IDs" collectionFormat(multi)
+// @Param director query string false "Director name"
+// @Param director_id query []int false "Director IDs" collectionFormat(multi)
+// @Param created_from query string false "Created at or after (RFC 3339)"
+// @Param created_to query string false "Created before (RFC 3339)"
+// @Param updated_from query string false "Updated at or after (RFC 3339)"
+// @Param updated_to query string false "Updated before (RFC 3339)"
+// @Param sort query string false "Sort order, e.g. rating:desc,year:asc. Columns: title, year, rating, rating_count, director, genre, created_at, updated_at, relevance"
+// @Param pagination query string false "Pagination mode" Enums(offset, cursor) default(offset)
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor, implies cursor pagination; only valid with the sort and filters it was issued for"
+// @Param count query string false "Total mode, estimate reads planner statistics" Enums(exact, estimate) default(exact)
 // @Success 200 {object} common.ResponseWithList
+// @Failure 400 {object} common.ResponseError
 // @Failure 500 {object} common.ResponseError
 // @Router /api/v1/movies [get]
 func (h *handler) GetAll(c *gin.Context) {
@@ -186,13 +251,54 @@ func (h *handler) GetAll(c *gin.Context) {
 		limit = 10
 	}
 
-	var req common.RequestSearch
-	req.Page = page
-	req.Limit = limit
-	req.Search = c.Query("search")
+	filter, err := parseMovieFilter(c)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	filter.Page = page
+	filter.Limit = limit
+	filter.CursorMode = c.Query("pagination") == "cursor"
+	filter.EstimateCount = c.Query("count") == "estimate"
+	filter.ViewerID = c.GetUint("user_id")
 
-	movies, err := h.service.GetAll(req)
+	if token := c.Query("cursor"); token != "" {
+		cursor, err := h.service.DecodeCursor(token)
+		if err != nil {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Invalid cursor",
+				},
+			)
+			return
+		}
+
+		filter.Cursor = cursor
+		filter.CursorMode = true
+	}
+
+	movies, err := h.service.GetAll(filter)
 	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
 		c.JSON(
 			http.StatusInternalServerError,
 			common.ResponseError{
@@ -206,14 +312,63 @@ func (h *handler) GetAll(c *gin.Context) {
 	c.JSON(
 		http.StatusOK,
 		common.ResponseWithList{
-			Status:  http.StatusOK,
-			Message: "Movies fetched successfully",
-			Total:   movies.Total,
-			Data:    movies.Movies,
+			Status:     http.StatusOK,
+			Message:    "Movies fetched successfully",
+			Total:      movies.Total,
+			Estimated:  movies.Estimated,
+			Data:       movies.Movies,
+			NextCursor: movies.NextCursor,
+			PrevCursor: movies.PrevCursor,
 		},
 	)
 }
 
+func parseMovieFilter(c *gin.Context) (MovieFilter, error) {
+	var filter MovieFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		return filter, errors.Wrap(err, "invalid filter")
+	}
+
+	genres := make([]string, 0, len(filter.Genres))
+	for _, value := range filter.Genres {
+		for _, genre := range strings.Split(value, ",") {
+			if genre = strings.TrimSpace(genre); genre != "" {
+				genres = append(genres, strings.ToLower(genre))
+			}
+		}
+	}
+	filter.Genres = genres
+	filter.Director = strings.ToLower(strings.TrimSpace(filter.Director))
+
+	if err := common.Validate.Struct(filter); err != nil {
+		return filter, err
+	}
+
+	if filter.YearFrom != nil && filter.YearTo != nil && *filter.YearFrom > *filter.YearTo {
+		return filter, errors.New("year_from must not be greater than year_to")
+	}
+	if filter.RatingMin != nil && filter.RatingMax != nil && *filter.RatingMin > *filter.RatingMax {
+		return filter, errors.New("rating_min must not be greater than rating_max")
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && filter.CreatedFrom.After(*filter.CreatedTo) {
+		return filter, errors.New("created_from must not be after created_to")
+	}
+	if filter.UpdatedFrom != nil && filter.UpdatedTo != nil && filter.UpdatedFrom.After(*filter.UpdatedTo) {
+		return filter, errors.New("updated_from must not be after updated_to")
+	}
+
+	sort, err := common.ParseSort(c.Query("sort"), MovieSortColumns)
+	if err != nil {
+		return filter, err
+	}
+	filter.Sort = sort
+
+	filter.Search = strings.TrimSpace(c.Query("search"))
+	filter.Highlight, _ = strconv.ParseBool(c.Query("highlight"))
+
+	return filter, nil
+}
+
 // @Summary Update a movie by ID
 // @Description Update a movie by ID
 // @Tags movies
@@ -221,9 +376,13 @@ func (h *handler) GetAll(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Movie ID"
 // @Param movie body MovieUpdateInput true "Movie"
+// @Param If-Match header string false "ETag of the version the change is based on"
 // @Success 200 {object} common.ResponseID
+// @Header 200 {string} ETag "New version of the movie"
 // @Failure 400 {object} common.ResponseError
 // @Failure 404 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 412 {object} common.Response{data=movie.MovieResponse} "The movie changed, data holds its current state"
 // @Failure 500 {object} common.ResponseError
 // @Security ApiKeyAuth
 // @Router /api/v1/movies/{id} [put]
@@ -277,8 +436,25 @@ func (h *handler) Update(c *gin.Context) {
 	}
 
 	req.ID = uint(idUint)
-	movie, err := h.service.Update(req)
+	req.IfMatch = ifMatchVersions(c)
+	movie, err := h.service.Update(req, audit.ActorFrom(c))
 	if err != nil {
+		if errors.Is(err, ErrVersionMismatch) {
+			h.preconditionFailed(c, req.ID)
+			return
+		}
+
+		if errors.Is(err, genre.ErrNotFound) || errors.Is(err, person.ErrNotFound) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(
 				http.StatusNotFound,
@@ -300,6 +476,7 @@ func (h *handler) Update(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", movieETag(movie.Version))
 	c.JSON(
 		http.StatusOK,
 		common.ResponseID{
@@ -310,15 +487,172 @@ func (h *handler) Update(c *gin.Context) {
 	)
 }
 
+// patchMaxBytes bounds the size of a patch document.
+const patchMaxBytes = 1 << 20
+
+// @Summary Patch a movie by ID
+// @Description Change part of a movie with a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), chosen by the Content-Type; plain application/json is read as a merge patch. The patch applies to the movie in the shape of the update body, with genres and directors as {id, name} lists, and the result must pass the same rules as an update. Editing the name of a genre or director relinks the movie to the one with that name. Returns the updated movie
+// @Tags movies
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param patch body object true "Merge patch object or JSON Patch operations"
+// @Param If-Match header string false "ETag of the version the change is based on"
+// @Success 200 {object} common.Response{data=movie.MovieResponse}
+// @Header 200 {string} ETag "New version of the movie"
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 409 {object} common.ResponseError "A test operation failed"
+// @Failure 412 {object} common.Response{data=movie.MovieResponse} "The movie changed, data holds its current state"
+// @Failure 413 {object} common.ResponseError
+// @Failure 415 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id} [patch]
+func (h *handler) Patch(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	format := c.ContentType()
+	switch format {
+	case PatchFormatMerge, PatchFormatJSON:
+	case "application/json":
+		format = PatchFormatMerge
+	default:
+		c.JSON(
+			http.StatusUnsupportedMediaType,
+			common.ResponseError{
+				Status:  http.StatusUnsupportedMediaType,
+				Message: "Patch must be " + PatchFormatMerge + " or " + PatchFormatJSON,
+			},
+		)
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, patchMaxBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(
+				http.StatusRequestEntityTooLarge,
+				common.ResponseError{
+					Status:  http.StatusRequestEntityTooLarge,
+					Message: "Patch is too large",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	movie, err := h.service.Patch(MoviePatchInput{
+		ID:      uint(idUint),
+		IfMatch: ifMatchVersions(c),
+		Format:  format,
+		Patch:   patch,
+	}, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, ErrVersionMismatch) {
+			h.preconditionFailed(c, uint(idUint))
+			return
+		}
+
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			c.JSON(
+				http.StatusConflict,
+				common.ResponseError{
+					Status:  http.StatusConflict,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrInvalidPatch) || errors.Is(err, genre.ErrNotFound) || errors.Is(err, person.ErrNotFound) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie not found",
+				},
+			)
+			return
+		}
+
+		if helper.ErrorIs(err, "duplicate") {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Already exists",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.Header("ETag", movieETag(movie.Version))
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "Movie updated successfully",
+			Data:    movie,
+		},
+	)
+}
+
 // @Summary Delete a movie by ID
 // @Description Delete a movie by ID
 // @Tags movies
 // @Accept json
 // @Produce json
 // @Param id path string true "Movie ID"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Success 200 {object} common.ResponseID
 // @Failure 400 {object} common.ResponseError
 // @Failure 404 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 412 {object} common.Response{data=movie.MovieResponse} "The movie changed, data holds its current state"
 // @Failure 500 {object} common.ResponseError
 // @Security ApiKeyAuth
 // @Router /api/v1/movies/{id} [delete]
@@ -348,8 +682,13 @@ func (h *handler) Delete(c *gin.Context) {
 		return
 	}
 
-	movie, err := h.service.Delete(common.RequestID{ID: uint(idUint)})
+	movie, err := h.service.Delete(common.RequestID{ID: uint(idUint)}, ifMatchVersions(c), audit.ActorFrom(c))
 	if err != nil {
+		if errors.Is(err, ErrVersionMismatch) {
+			h.preconditionFailed(c, uint(idUint))
+			return
+		}
+
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(
 				http.StatusNotFound,
@@ -380,3 +719,450 @@ func (h *handler) Delete(c *gin.Context) {
 		},
 	)
 }
+
+// movieETag is the entity tag of a movie version. It is a strong tag, so
+// clients can send it back in If-Match.
+func movieETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersions reads the If-Match header as movie versions. Without the
+// header, or with "*", it returns nil and writes are unconditional. Weak or
+// unknown tags never match, so a header of only those gives an empty list.
+func ifMatchVersions(c *gin.Context) []int64 {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := make([]int64, 0, 1)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// preconditionFailed answers a write based on a stale version with the
+// current state of the movie, so the client can merge and retry.
+func (h *handler) preconditionFailed(c *gin.Context, id uint) {
+	movie, err := h.service.GetByID(common.RequestID{ID: id}, false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.Header("ETag", movieETag(movie.Version))
+	c.JSON(
+		http.StatusPreconditionFailed,
+		common.Response{
+			Status:  http.StatusPreconditionFailed,
+			Message: "Movie was changed by someone else, merge with the current version and retry",
+			Data:    movie,
+		},
+	)
+}
+
+// importMaxBytes bounds the size of an uploaded import file.
+const importMaxBytes = 64 << 20
+
+// @Summary Import movies
+// @Description Upsert movies on title from a CSV or NDJSON body, in batches. CSV needs a header row with title and year, and optional genre and director columns whose cells separate several names with "|". NDJSON takes one movie object per line, shaped like the create body. The report lists every row as created, updated or rejected. Batches written before a failure stay written, and the error response carries the report of them
+// @Tags movies
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "Body format, defaults from the Content-Type" Enums(csv, ndjson)
+// @Param dry_run query bool false "Validate and report without writing"
+// @Success 200 {object} common.Response{data=movie.ImportReport}
+// @Failure 400 {object} common.Response{data=movie.ImportReport}
+// @Failure 403 {object} common.ResponseError
+// @Failure 413 {object} common.Response{data=movie.ImportReport}
+// @Failure 500 {object} common.Response{data=movie.ImportReport}
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/import [post]
+func (h *handler) Import(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		switch c.ContentType() {
+		case "text/csv":
+			format = ImportFormatCSV
+		case "application/x-ndjson", "application/jsonl", "application/json":
+			format = ImportFormatNDJSON
+		}
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	// A large file takes longer to stream than the server timeouts allow, and
+	// cutting it off would leave the import half written without a report.
+	// Clearing the deadlines fails only where they are not supported.
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, importMaxBytes)
+	report, err := h.service.Import(body, format, dryRun, audit.ActorFrom(c))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(
+				http.StatusRequestEntityTooLarge,
+				common.Response{
+					Status:  http.StatusRequestEntityTooLarge,
+					Message: "Import file is too large, the report lists the rows written before the limit",
+					Data:    report,
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrImportFormat) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.Response{
+					Status:  http.StatusBadRequest,
+					Message: err.Error(),
+					Data:    report,
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.Response{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+				Data:    report,
+			},
+		)
+		return
+	}
+
+	message := "Movies imported successfully"
+	if dryRun {
+		message = "Import checked successfully, nothing was written"
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: message,
+			Data:    report,
+		},
+	)
+}
+
+var exportContentTypes = map[string]string{
+	ExportFormatCSV:    "text/csv; charset=utf-8",
+	ExportFormatNDJSON: "application/x-ndjson",
+	ExportFormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// @Summary Export movies
+// @Description Stream every movie matching the filters of the list endpoint as a file download, in the list sort order
+// @Tags movies
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "File format" Enums(csv, ndjson, xlsx) default(csv)
+// @Param columns query string false "Comma separated columns out of id, title, year, genre, director, rating, rating_count, created_at, updated_at"
+// @Param filename query string false "Download file name, without extension"
+// @Param search query string false "Search"
+// @Param sort query string false "Sort"
+// @Param genre query []string false "Genre names" collectionFormat(multi)
+// @Param genre_id query []int false "Genre IDs" collectionFormat(multi)
+// @Param director query string false "Director"
+// @Param director_id query []int false "Director IDs" collectionFormat(multi)
+// @Param year_from query int false "Year from"
+// @Param year_to query int false "Year to"
+// @Param rating_min query number false "Minimum rating"
+// @Param rating_max query number false "Maximum rating"
+// @Success 200 {file} file
+// @Failure 400 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/export [get]
+func (h *handler) Export(c *gin.Context) {
+	filter, err := parseMovieFilter(c)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	format := c.DefaultQuery("format", ExportFormatCSV)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid format",
+			},
+		)
+		return
+	}
+
+	columns, err := ParseExportColumns(c.Query("columns"))
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	name := exportFilename(c.Query("filename"))
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+
+	// A large catalogue takes longer to stream than the server write timeout.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	if err := h.service.Export(filter, format, columns, c.Writer); err != nil {
+		if c.Writer.Written() {
+			// The download has started and its status can no longer change,
+			// so the file just ends early.
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Header("Content-Disposition", "")
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+}
+
+// exportFilename keeps the requested download name to safe characters, or
+// names the file after the current time.
+func exportFilename(requested string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return -1
+		}
+	}, requested)
+	name = strings.Trim(name, ".")
+
+	if name == "" {
+		return "movies-" + time.Now().UTC().Format("20060102-150405")
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return name
+}
+
+// @Summary List deleted movies
+// @Description List the movies in the trash, most recently deleted first. purge_at tells when the retention job removes each one for good
+// @Tags admin
+// @Produce json
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(10)
+// @Success 200 {object} common.ResponseWithList{data=[]movie.TrashedMovieResponse}
+// @Failure 403 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/trash/movies [get]
+func (h *handler) ListTrash(c *gin.Context) {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	movies, err := h.service.ListTrash(TrashFilter{Page: page, Limit: limit})
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseWithList{
+			Status:  http.StatusOK,
+			Message: "Deleted movies fetched successfully",
+			Data:    movies.Movies,
+			Total:   movies.Total,
+		},
+	)
+}
+
+// @Summary Restore a deleted movie
+// @Description Bring a movie back from the trash. Fails with 409 when a live movie has taken its title meanwhile
+// @Tags admin
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 409 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/trash/movies/{id}/restore [post]
+func (h *handler) Restore(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	movie, err := h.service.Restore(common.RequestID{ID: uint(idUint)}, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie is not in the trash",
+				},
+			)
+			return
+		}
+
+		if helper.ErrorIs(err, "duplicate") {
+			c.JSON(
+				http.StatusConflict,
+				common.ResponseError{
+					Status:  http.StatusConflict,
+					Message: "Another movie already has this title",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Movie restored successfully",
+			ID:      movie.ID,
+		},
+	)
+}
+
+// @Summary Purge a deleted movie
+// @Description Remove a movie from the trash for good, with its credits, ratings, reviews, watchlist entries and images. Only deleted movies can be purged
+// @Tags admin
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/trash/movies/{id} [delete]
+func (h *handler) Purge(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	movie, err := h.service.Purge(common.RequestID{ID: uint(idUint)}, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie is not in the trash",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Movie purged successfully",
+			ID:      movie.ID,
+		},
+	)
+}
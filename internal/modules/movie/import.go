@@ -0,0 +1,426 @@
+package movie
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/genre"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// importBatchSize is how many rows are upserted per transaction.
+const importBatchSize = 500
+
+// importMaxLine bounds a single NDJSON line.
+const importMaxLine = 1 << 20
+
+var ErrImportFormat = errors.New("invalid import file")
+
+type importRow struct {
+	line  int
+	input MovieCreateInput
+	err   error
+}
+
+// importReader streams rows out of an uploaded file. A row that cannot be
+// parsed is returned with err set; Next only fails when the file as a whole
+// cannot be read any further.
+type importReader interface {
+	Next() (*importRow, error)
+}
+
+func newImportReader(src io.Reader, format string) (importReader, error) {
+	switch format {
+	case ImportFormatCSV:
+		return newCSVReader(src)
+	case ImportFormatNDJSON:
+		scanner := bufio.NewScanner(src)
+		scanner.Buffer(make([]byte, 0, 64*1024), importMaxLine)
+		return &ndjsonReader{scanner: scanner}, nil
+	default:
+		return nil, errors.Wrapf(ErrImportFormat, "unknown format %q", format)
+	}
+}
+
+// csvReader reads a header row naming the columns title, year, genre and
+// director. Genre and director cells may list several names separated by "|".
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(src io.Reader) (*csvReader, error) {
+	r := csv.NewReader(src)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, errors.Wrap(ErrImportFormat, "missing header row")
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"title", "year"} {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.Wrapf(ErrImportFormat, "missing %s column", name)
+		}
+	}
+
+	return &csvReader{r: r, columns: columns}, nil
+}
+
+func (c *csvReader) Next() (*importRow, error) {
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return &importRow{line: parseErr.StartLine, err: parseErr.Err}, nil
+		}
+		return nil, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	row := &importRow{line: line}
+	row.input.Title = c.cell(record, "title")
+	row.input.Genres = names(c.cell(record, "genre"))
+	row.input.Directors = names(c.cell(record, "director"))
+
+	if year := c.cell(record, "year"); year != "" {
+		if row.input.Year, err = strconv.Atoi(year); err != nil {
+			row.err = errors.Errorf("invalid year %q", year)
+		}
+	}
+	return row, nil
+}
+
+func (c *csvReader) cell(record []string, column string) string {
+	i, ok := c.columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func names(cell string) []common.RequestRef {
+	var refs []common.RequestRef
+	for _, name := range strings.Split(cell, "|") {
+		if name = strings.TrimSpace(name); name != "" {
+			refs = append(refs, common.RequestRef{Name: name})
+		}
+	}
+	return refs
+}
+
+// ndjsonReader reads one MovieCreateInput object per line. Blank lines are
+// skipped.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (n *ndjsonReader) Next() (*importRow, error) {
+	for n.scanner.Scan() {
+		n.line++
+		text := strings.TrimSpace(n.scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		row := &importRow{line: n.line}
+		if err := json.Unmarshal([]byte(text), &row.input); err != nil {
+			row.err = errors.New("invalid json")
+		}
+		return row, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, errors.Wrapf(ErrImportFormat, "line %d is too long", n.line+1)
+		}
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Import upserts movies on title from a CSV or NDJSON stream, in batches of
+// importBatchSize rows. Rows are validated like MovieCreateInput and rejected
+// rows are reported without failing the import. With dryRun nothing is
+// written, and named genres and directors are reported as if created. An error
+// comes with the report of the batches written before it, which stay written.
+func (s *service) Import(src io.Reader, format string, dryRun bool, actor audit.Actor) (*ImportReport, error) {
+	rows, err := newImportReader(src, format)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := importer{
+		service:       s,
+		actor:         actor,
+		dryRun:        dryRun,
+		report:        &ImportReport{DryRun: dryRun, Rows: []ImportRowReport{}},
+		genreCache:    map[string]genre.Genre{},
+		directorCache: map[string]person.Person{},
+		titles:        map[string]bool{},
+	}
+
+	batch := make([]*importRow, 0, importBatchSize)
+	for {
+		row, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imp.report, err
+		}
+
+		if row.err == nil {
+			row.err = common.Validate.Struct(row.input)
+		}
+		if row.err != nil {
+			imp.reject(row, row.err)
+			continue
+		}
+
+		batch = append(batch, row)
+		if len(batch) == importBatchSize {
+			if err := imp.flush(batch); err != nil {
+				return imp.report, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if err := imp.flush(batch); err != nil {
+		return imp.report, err
+	}
+	return imp.report, nil
+}
+
+type importer struct {
+	*service
+	actor  audit.Actor
+	dryRun bool
+	report *ImportReport
+
+	// genreCache and directorCache hold resolved references by key, so a
+	// catalogue naming the same genre thousands of times resolves it once.
+	genreCache    map[string]genre.Genre
+	directorCache map[string]person.Person
+
+	// titles records the titles of a dry run, so a repeated title counts as
+	// an update like it would for real.
+	titles map[string]bool
+}
+
+func (imp *importer) flush(batch []*importRow) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	accepted := make([]*importRow, 0, len(batch))
+	movies := make([]Movie, 0, len(batch))
+	for _, row := range batch {
+		movie, err := imp.movie(row.input)
+		if err != nil {
+			if !errors.Is(err, genre.ErrNotFound) && !errors.Is(err, person.ErrNotFound) {
+				return err
+			}
+			imp.reject(row, err)
+			continue
+		}
+		accepted = append(accepted, row)
+		movies = append(movies, movie)
+	}
+
+	if imp.dryRun {
+		return imp.classify(accepted)
+	}
+
+	// The batch and its audit entries are written in one transaction, and
+	// reported only once it has committed.
+	var results []importResult
+	err := imp.repo.Transaction(func(repo Repository, tx *gorm.DB) error {
+		before, err := existing(repo, accepted)
+		if err != nil {
+			return err
+		}
+
+		if results, err = repo.Import(movies); err != nil {
+			return err
+		}
+
+		ids := make([]uint, 0, len(results))
+		for _, result := range results {
+			if result.Err == nil {
+				ids = append(ids, result.ID)
+			}
+		}
+		after, err := repo.Snapshots(ids)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			if result.Err != nil {
+				continue
+			}
+			action, from := audit.ActionUpdate, before
+			if result.Created {
+				action, from = audit.ActionCreate, nil
+			}
+			if err := imp.record(tx, imp.actor, action, result.ID, from, after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		if result.Err != nil {
+			imp.reject(accepted[i], result.Err)
+			continue
+		}
+		imp.accept(accepted[i], result.ID, result.Created)
+	}
+	return nil
+}
+
+// existing snapshots the movies the rows are about to update, for the audit
+// log.
+func existing(repo Repository, rows []*importRow) (map[uint]MovieSnapshot, error) {
+	titles := make([]string, len(rows))
+	for i, row := range rows {
+		titles[i] = row.input.Title
+	}
+	found, err := repo.FindByTitles(titles)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(found))
+	for _, id := range found {
+		ids = append(ids, id)
+	}
+	return repo.Snapshots(ids)
+}
+
+func (imp *importer) movie(input MovieCreateInput) (Movie, error) {
+	genreRefs := withName(input.Genres, input.Genre)
+	directorRefs := withName(input.Directors, input.Director)
+
+	if imp.dryRun {
+		if err := imp.genres.Check(genreRefs); err != nil {
+			return Movie{}, err
+		}
+		if err := imp.people.Check(directorRefs); err != nil {
+			return Movie{}, err
+		}
+		return Movie{Title: input.Title, Year: input.Year}, nil
+	}
+
+	genres, err := resolveCached(genreRefs, imp.genreCache, genre.NormalizeName, imp.genres.Resolve, func(g genre.Genre) uint { return g.ID })
+	if err != nil {
+		return Movie{}, err
+	}
+	directors, err := resolveCached(directorRefs, imp.directorCache, person.NormalizeName, imp.people.Resolve, func(p person.Person) uint { return p.ID })
+	if err != nil {
+		return Movie{}, err
+	}
+
+	return Movie{Title: input.Title, Year: input.Year, Genres: genres, Directors: directors}, nil
+}
+
+func (imp *importer) classify(rows []*importRow) error {
+	titles := make([]string, len(rows))
+	for i, row := range rows {
+		titles[i] = row.input.Title
+	}
+	existing, err := imp.repo.FindByTitles(titles)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		id, found := existing[row.input.Title]
+		found = found || imp.titles[row.input.Title]
+		imp.titles[row.input.Title] = true
+		imp.accept(row, id, !found)
+	}
+	return nil
+}
+
+func (imp *importer) accept(row *importRow, id uint, created bool) {
+	result := ImportUpdated
+	if created {
+		result = ImportCreated
+		imp.report.Created++
+	} else {
+		imp.report.Updated++
+	}
+	imp.report.Rows = append(imp.report.Rows, ImportRowReport{
+		Line:   row.line,
+		Title:  row.input.Title,
+		Result: result,
+		ID:     id,
+	})
+}
+
+func (imp *importer) reject(row *importRow, err error) {
+	imp.report.Rejected++
+	imp.report.Rows = append(imp.report.Rows, ImportRowReport{
+		Line:   row.line,
+		Title:  row.input.Title,
+		Result: ImportRejected,
+		Reason: err.Error(),
+	})
+}
+
+// resolveCached resolves references one at a time through resolve, reusing
+// earlier results from cache. Duplicates are dropped like Resolve does.
+func resolveCached[T any](refs []common.RequestRef, cache map[string]T, normalize func(string) string, resolve func([]common.RequestRef) ([]T, error), id func(T) uint) ([]T, error) {
+	values := make([]T, 0, len(refs))
+	seen := make(map[uint]bool, len(refs))
+
+	for _, ref := range refs {
+		key := "name:" + normalize(ref.Name)
+		if ref.ID != 0 {
+			key = "id:" + strconv.FormatUint(uint64(ref.ID), 10)
+		}
+
+		value, ok := cache[key]
+		if !ok {
+			resolved, err := resolve([]common.RequestRef{ref})
+			if err != nil {
+				return nil, err
+			}
+			value = resolved[0]
+			cache[key] = value
+		}
+
+		if !seen[id(value)] {
+			seen[id(value)] = true
+			values = append(values, value)
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,261 @@
+package movie
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/pkg/errors"
+)
+
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+func (c *conditions) add(clause string, args ...interface{}) {
+	c.clauses = append(c.clauses, clause)
+	c.args = append(c.args, args...)
+}
+
+func (c *conditions) sql() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// filterConditions turns the structured filter into parameterized conditions.
+// The search term is not included since its condition depends on the search
+// mode.
+func filterConditions(filter MovieFilter) *conditions {
+	c := &conditions{}
+	c.add("deleted_at IS NULL")
+
+	if filter.YearFrom != nil {
+		c.add("year >= ?", *filter.YearFrom)
+	}
+	if filter.YearTo != nil {
+		c.add("year <= ?", *filter.YearTo)
+	}
+	if filter.RatingMin != nil {
+		c.add("rating >= ?", *filter.RatingMin)
+	}
+	if filter.RatingMax != nil {
+		c.add("rating <= ?", *filter.RatingMax)
+	}
+	if len(filter.Genres) > 0 {
+		c.add(`id IN (
+			SELECT mg.movie_id FROM movie_genres mg
+			JOIN genres g ON g.id = mg.genre_id AND g.deleted_at IS NULL
+			WHERE g.name IN ?
+		)`, filter.Genres)
+	}
+	if len(filter.GenreIDs) > 0 {
+		c.add("id IN (SELECT movie_id FROM movie_genres WHERE genre_id IN ?)", filter.GenreIDs)
+	}
+	if filter.Director != "" {
+		c.add(`id IN (
+			SELECT md.movie_id FROM movie_directors md
+			JOIN people p ON p.id = md.person_id AND p.deleted_at IS NULL
+			WHERE lower(p.name) = ?
+		)`, filter.Director)
+	}
+	if len(filter.DirectorIDs) > 0 {
+		c.add("id IN (SELECT movie_id FROM movie_directors WHERE person_id IN ?)", filter.DirectorIDs)
+	}
+	if filter.CreatedFrom != nil {
+		c.add("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		c.add("created_at < ?", *filter.CreatedTo)
+	}
+	if filter.UpdatedFrom != nil {
+		c.add("updated_at >= ?", *filter.UpdatedFrom)
+	}
+	if filter.UpdatedTo != nil {
+		c.add("updated_at < ?", *filter.UpdatedTo)
+	}
+
+	return c
+}
+
+// sortFields resolves the effective sort: the requested one, or relevance for
+// searches and newest first otherwise. The id is always appended as a
+// tie-breaker so the order is total, which keyset pagination relies on.
+func sortFields(filter MovieFilter) []common.SortField {
+	requested := filter.Sort
+	if len(requested) == 0 {
+		if filter.Search != "" {
+			requested = []common.SortField{{Column: "relevance", Desc: true}}
+		} else {
+			requested = []common.SortField{{Column: "created_at", Desc: true}}
+		}
+	}
+
+	fields := make([]common.SortField, 0, len(requested)+1)
+	idDesc := true
+	for _, field := range requested {
+		if field.Column == "relevance" && filter.Search == "" {
+			continue
+		}
+		fields = append(fields, field)
+		idDesc = field.Desc
+	}
+
+	return append(fields, common.SortField{Column: "id", Desc: idDesc})
+}
+
+func sortSpec(fields []common.SortField) string {
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		direction := "asc"
+		if field.Desc {
+			direction = "desc"
+		}
+		parts = append(parts, field.Column+":"+direction)
+	}
+	return strings.Join(parts, ",")
+}
+
+// filterSpec digests the conditions a list was filtered by, search included,
+// so that a cursor issued for them is not replayed against another filter.
+// Paging and presentation parameters are left out.
+func filterSpec(filter MovieFilter) string {
+	payload, _ := json.Marshal([]interface{}{
+		filter.Search,
+		filter.YearFrom, filter.YearTo,
+		filter.RatingMin, filter.RatingMax,
+		filter.Genres, filter.GenreIDs,
+		filter.Director, filter.DirectorIDs,
+		filter.CreatedFrom, filter.CreatedTo,
+		filter.UpdatedFrom, filter.UpdatedTo,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:16])
+}
+
+// checkCursor rejects a cursor issued for another sort or filter, or whose
+// values do not parse for the sort.
+func checkCursor(c MovieCursor, fields []common.SortField, spec, filterHash string) error {
+	if c.Sort != spec {
+		return errors.Wrap(ErrInvalidCursor, "cursor was issued for a different sort")
+	}
+	if c.Filter != filterHash {
+		return errors.Wrap(ErrInvalidCursor, "cursor was issued for a different filter")
+	}
+	_, err := cursorValues(c, fields)
+	return err
+}
+
+// orderBy renders the sort. Backward pages are read in reverse order and
+// flipped afterwards.
+func orderBy(fields []common.SortField, backward bool) string {
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		direction := "ASC"
+		if field.Desc != backward {
+			direction = "DESC"
+		}
+		parts = append(parts, field.Column+" "+direction)
+	}
+	return "ORDER BY " + strings.Join(parts, ", ")
+}
+
+// keyset renders the condition selecting the rows after (or before, when
+// backward) the cursor position, expanded to support mixed directions:
+// (a > ?) OR (a = ? AND b < ?) OR ...
+func keyset(fields []common.SortField, values []interface{}, backward bool) (string, []interface{}) {
+	ors := make([]string, 0, len(fields))
+	args := make([]interface{}, 0)
+
+	for i, field := range fields {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, fields[j].Column+" = ?")
+			args = append(args, values[j])
+		}
+
+		op := ">"
+		if field.Desc != backward {
+			op = "<"
+		}
+		ands = append(ands, field.Column+" "+op+" ?")
+		args = append(args, values[i])
+
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func cursorValue(movie MovieResponse, column string) string {
+	switch column {
+	case "id":
+		return strconv.FormatUint(uint64(movie.ID), 10)
+	case "title":
+		return movie.Title
+	case "director":
+		return movie.Director
+	case "genre":
+		return movie.Genre
+	case "year":
+		return strconv.Itoa(movie.Year)
+	case "rating_count":
+		return strconv.FormatInt(movie.RatingCount, 10)
+	case "rating":
+		return strconv.FormatFloat(movie.Rating, 'g', -1, 64)
+	case "relevance":
+		if movie.Relevance == nil {
+			return "0"
+		}
+		return strconv.FormatFloat(*movie.Relevance, 'g', -1, 64)
+	case "created_at":
+		return movie.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		return movie.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		return ""
+	}
+}
+
+func cursorValues(cursor MovieCursor, fields []common.SortField) ([]interface{}, error) {
+	if len(cursor.Values) != len(fields) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		raw := cursor.Values[i]
+
+		var (
+			value interface{}
+			err   error
+		)
+		switch field.Column {
+		case "id":
+			value, err = strconv.ParseUint(raw, 10, 64)
+		case "year":
+			value, err = strconv.Atoi(raw)
+		case "rating_count":
+			value, err = strconv.ParseInt(raw, 10, 64)
+		case "rating", "relevance":
+			value, err = strconv.ParseFloat(raw, 64)
+		case "created_at", "updated_at":
+			value, err = time.Parse(time.RFC3339Nano, raw)
+		default:
+			value = raw
+		}
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidCursor, err.Error())
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
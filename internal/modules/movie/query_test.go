@@ -0,0 +1,376 @@
+package movie
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/pkg/errors"
+)
+
+func asc(column string) common.SortField  { return common.SortField{Column: column} }
+func desc(column string) common.SortField { return common.SortField{Column: column, Desc: true} }
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestFilterConditions(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name    string
+		filter  MovieFilter
+		clauses []string
+		args    []interface{}
+	}{
+		{
+			name:    "no filter keeps trashed movies out",
+			clauses: []string{"deleted_at IS NULL"},
+			args:    []interface{}{},
+		},
+		{
+			name:    "search and paging add nothing",
+			filter:  MovieFilter{RequestSearch: common.RequestSearch{Search: "alien", Page: 2, Limit: 10}},
+			clauses: []string{"deleted_at IS NULL"},
+			args:    []interface{}{},
+		},
+		{
+			name:    "year and rating ranges are inclusive",
+			filter:  MovieFilter{YearFrom: intPtr(1970), YearTo: intPtr(1989), RatingMin: floatPtr(7.5), RatingMax: floatPtr(9)},
+			clauses: []string{"deleted_at IS NULL", "year >= ?", "year <= ?", "rating >= ?", "rating <= ?"},
+			args:    []interface{}{1970, 1989, 7.5, 9.0},
+		},
+		{
+			name:    "a zero bound is still a bound",
+			filter:  MovieFilter{YearFrom: intPtr(0), RatingMax: floatPtr(0)},
+			clauses: []string{"deleted_at IS NULL", "year >= ?", "rating <= ?"},
+			args:    []interface{}{0, 0.0},
+		},
+		{
+			name:    "genres by name and by id",
+			filter:  MovieFilter{Genres: []string{"horror", "drama"}, GenreIDs: []uint{3}},
+			clauses: []string{"deleted_at IS NULL", "WHERE g.name IN ?", "genre_id IN ?"},
+			args:    []interface{}{[]string{"horror", "drama"}, []uint{3}},
+		},
+		{
+			name:    "directors by name and by id",
+			filter:  MovieFilter{Director: "ridley scott", DirectorIDs: []uint{4, 5}},
+			clauses: []string{"deleted_at IS NULL", "WHERE lower(p.name) = ?", "person_id IN ?"},
+			args:    []interface{}{"ridley scott", []uint{4, 5}},
+		},
+		{
+			name:    "empty lists are ignored",
+			filter:  MovieFilter{Genres: []string{}, GenreIDs: []uint{}, DirectorIDs: []uint{}},
+			clauses: []string{"deleted_at IS NULL"},
+			args:    []interface{}{},
+		},
+		{
+			name:    "time ranges include the start and exclude the end",
+			filter:  MovieFilter{CreatedFrom: &from, CreatedTo: &to, UpdatedFrom: &from, UpdatedTo: &to},
+			clauses: []string{"deleted_at IS NULL", "created_at >= ?", "created_at < ?", "updated_at >= ?", "updated_at < ?"},
+			args:    []interface{}{from, to, from, to},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := filterConditions(tt.filter)
+			if len(c.clauses) != len(tt.clauses) {
+				t.Fatalf("clauses = %q, want %q", c.clauses, tt.clauses)
+			}
+			for i, want := range tt.clauses {
+				if !strings.Contains(c.clauses[i], want) {
+					t.Errorf("clause %d = %q, want it to contain %q", i, c.clauses[i], want)
+				}
+			}
+			if len(c.args) != len(tt.args) || (len(c.args) > 0 && !reflect.DeepEqual(c.args, tt.args)) {
+				t.Errorf("args = %v, want %v", c.args, tt.args)
+			}
+			if sql := c.sql(); !strings.HasPrefix(sql, "WHERE deleted_at IS NULL") {
+				t.Errorf("sql = %q", sql)
+			}
+		})
+	}
+
+	if sql := (&conditions{}).sql(); sql != "" {
+		t.Errorf("sql of no conditions = %q, want empty", sql)
+	}
+}
+
+func TestSortColumnsParse(t *testing.T) {
+	if _, err := common.ParseSort(strings.Join(MovieSortColumns, ","), MovieSortColumns); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := common.ParseSort("deleted_at", MovieSortColumns); err == nil {
+		t.Fatal("deleted_at is accepted as a sort column")
+	}
+}
+
+func TestSortFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter MovieFilter
+		want   []common.SortField
+	}{
+		{
+			name: "newest first by default",
+			want: []common.SortField{desc("created_at"), desc("id")},
+		},
+		{
+			name:   "relevance first when searching",
+			filter: MovieFilter{RequestSearch: common.RequestSearch{Search: "alien"}},
+			want:   []common.SortField{desc("relevance"), desc("id")},
+		},
+		{
+			name:   "id breaks ties in the direction of the last field",
+			filter: MovieFilter{Sort: []common.SortField{desc("year"), asc("title")}},
+			want:   []common.SortField{desc("year"), asc("title"), asc("id")},
+		},
+		{
+			name:   "relevance is dropped without a search",
+			filter: MovieFilter{Sort: []common.SortField{asc("title"), desc("relevance")}},
+			want:   []common.SortField{asc("title"), asc("id")},
+		},
+		{
+			name:   "only relevance without a search leaves the id",
+			filter: MovieFilter{Sort: []common.SortField{asc("relevance")}},
+			want:   []common.SortField{desc("id")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sortFields(tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	fields := []common.SortField{desc("year"), asc("title"), asc("id")}
+
+	if got, want := orderBy(fields, false), "ORDER BY year DESC, title ASC, id ASC"; got != want {
+		t.Errorf("forward: got %q, want %q", got, want)
+	}
+	if got, want := orderBy(fields, true), "ORDER BY year ASC, title DESC, id DESC"; got != want {
+		t.Errorf("backward: got %q, want %q", got, want)
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   []common.SortField
+		values   []interface{}
+		backward bool
+		want     string
+		args     []interface{}
+	}{
+		{
+			name:   "id alone",
+			fields: []common.SortField{asc("id")},
+			values: []interface{}{uint64(7)},
+			want:   "((id > ?))",
+			args:   []interface{}{uint64(7)},
+		},
+		{
+			name:   "ties on the sort column fall through to the id",
+			fields: []common.SortField{desc("created_at"), desc("id")},
+			values: []interface{}{"t", uint64(7)},
+			want:   "((created_at < ?) OR (created_at = ? AND id < ?))",
+			args:   []interface{}{"t", "t", uint64(7)},
+		},
+		{
+			name:   "mixed directions",
+			fields: []common.SortField{desc("year"), asc("title"), asc("id")},
+			values: []interface{}{1979, "Alien", uint64(7)},
+			want:   "((year < ?) OR (year = ? AND title > ?) OR (year = ? AND title = ? AND id > ?))",
+			args:   []interface{}{1979, 1979, "Alien", 1979, "Alien", uint64(7)},
+		},
+		{
+			name:     "backward flips every operator",
+			fields:   []common.SortField{desc("year"), asc("title"), asc("id")},
+			values:   []interface{}{1979, "Alien", uint64(7)},
+			backward: true,
+			want:     "((year > ?) OR (year = ? AND title < ?) OR (year = ? AND title = ? AND id < ?))",
+			args:     []interface{}{1979, 1979, "Alien", 1979, "Alien", uint64(7)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args := keyset(tt.fields, tt.values, tt.backward)
+			if got != tt.want {
+				t.Errorf("condition = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCursorValues(t *testing.T) {
+	relevance := 0.25
+	created := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)
+	movie := MovieResponse{
+		ID:          7,
+		Title:       "Alien",
+		Year:        1979,
+		Genre:       "horror",
+		Rating:      8.5,
+		RatingCount: 12,
+		Director:    "ridley scott",
+		CreatedAt:   created,
+		UpdatedAt:   created.Add(time.Hour),
+		Relevance:   &relevance,
+	}
+
+	tests := []struct {
+		column string
+		movie  MovieResponse
+		want   interface{}
+	}{
+		{column: "id", movie: movie, want: uint64(7)},
+		{column: "title", movie: movie, want: "Alien"},
+		{column: "genre", movie: movie, want: "horror"},
+		{column: "director", movie: movie, want: "ridley scott"},
+		{column: "year", movie: movie, want: 1979},
+		{column: "rating", movie: movie, want: 8.5},
+		{column: "rating_count", movie: movie, want: int64(12)},
+		{column: "relevance", movie: movie, want: 0.25},
+		{column: "created_at", movie: movie, want: created},
+		{column: "updated_at", movie: movie, want: created.Add(time.Hour)},
+		// Every sort column is NOT NULL except relevance, which a row read
+		// without a search lacks; it is paged as 0.
+		{column: "relevance", movie: MovieResponse{}, want: 0.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			fields := []common.SortField{asc(tt.column)}
+			c := MovieCursor{Values: []string{cursorValue(tt.movie, tt.column)}}
+
+			values, err := cursorValues(c, fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := values[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorValuesRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []common.SortField
+		values []string
+	}{
+		{name: "fewer values than fields", fields: []common.SortField{asc("title"), asc("id")}, values: []string{"7"}},
+		{name: "more values than fields", fields: []common.SortField{asc("id")}, values: []string{"Alien", "7"}},
+		{name: "id that is not a number", fields: []common.SortField{asc("id")}, values: []string{"seven"}},
+		{name: "negative id", fields: []common.SortField{asc("id")}, values: []string{"-7"}},
+		{name: "year that is not a number", fields: []common.SortField{asc("year")}, values: []string{"1979.5"}},
+		{name: "rating that is not a number", fields: []common.SortField{asc("rating")}, values: []string{"high"}},
+		{name: "time that is not RFC 3339", fields: []common.SortField{asc("created_at")}, values: []string{"2024-03-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := cursorValues(MovieCursor{Values: tt.values}, tt.fields)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestCheckCursor(t *testing.T) {
+	issued := MovieFilter{
+		YearFrom: intPtr(1970),
+		Genres:   []string{"horror"},
+		Sort:     []common.SortField{desc("year")},
+	}
+	fields := sortFields(issued)
+	c := MovieCursor{
+		Sort:   sortSpec(fields),
+		Filter: filterSpec(issued),
+		Values: []string{"1979", "7"},
+	}
+
+	tests := []struct {
+		name   string
+		filter func(f *MovieFilter)
+		values []string
+		err    bool
+	}{
+		{name: "same sort and filter", filter: func(f *MovieFilter) {}},
+		{
+			name:   "another page size and page",
+			filter: func(f *MovieFilter) { f.Limit, f.Page = 50, 3 },
+		},
+		{
+			name:   "another sort direction",
+			filter: func(f *MovieFilter) { f.Sort = []common.SortField{asc("year")} },
+			err:    true,
+		},
+		{
+			name:   "another sort column",
+			filter: func(f *MovieFilter) { f.Sort = []common.SortField{desc("rating")} },
+			err:    true,
+		},
+		{
+			name:   "another filter value",
+			filter: func(f *MovieFilter) { f.YearFrom = intPtr(1980) },
+			err:    true,
+		},
+		{
+			name:   "a filter dropped",
+			filter: func(f *MovieFilter) { f.Genres = nil },
+			err:    true,
+		},
+		{
+			name:   "a filter added",
+			filter: func(f *MovieFilter) { f.YearTo = intPtr(1990) },
+			err:    true,
+		},
+		{
+			name:   "a search added",
+			filter: func(f *MovieFilter) { f.Search = "alien" },
+			err:    true,
+		},
+		{
+			name:   "values that do not parse",
+			filter: func(f *MovieFilter) {},
+			values: []string{"1979", "seven"},
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := issued
+			tt.filter(&filter)
+			replayed := c
+			if tt.values != nil {
+				replayed.Values = tt.values
+			}
+
+			fields := sortFields(filter)
+			err := checkCursor(replayed, fields, sortSpec(fields), filterSpec(filter))
+			if tt.err && !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidCursor)
+			}
+			if !tt.err && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -1,19 +1,35 @@
 package movie
 
 import (
-	"fmt"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
 	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository interface {
 	Create(req Movie) (*common.ResponseID, error)
 	GetByID(req common.RequestID) (*Movie, error)
-	GetAll(req common.RequestSearch) (*MovieListResponse, error)
-	Update(req Movie) (*common.ResponseID, error)
-	Delete(req common.RequestID) (*common.ResponseID, error)
+	GetAll(filter MovieFilter) (*MovieListResponse, error)
+	Update(req Movie, ifMatch []int64) (*MovieVersion, error)
+	Delete(req common.RequestID, ifMatch []int64) (*common.ResponseID, error)
+	FindByTitles(titles []string) (map[string]uint, error)
+	Import(movies []Movie) ([]importResult, error)
+	Export(filter MovieFilter, each func(MovieResponse) error) error
+	ListTrash(filter TrashFilter) (*TrashListResponse, error)
+	Restore(req common.RequestID) (*common.ResponseID, error)
+	TrashedBefore(cutoff time.Time, limit int) ([]uint, error)
+	LockTrashed(ids []uint) ([]uint, error)
+	Purge(ids []uint) (int64, error)
+	Snapshots(ids []uint) (map[uint]MovieSnapshot, error)
+	MovieExists(id uint) (bool, error)
+	Transaction(fn func(repo Repository, tx *gorm.DB) error) error
 }
 
 type repository struct {
@@ -24,71 +40,658 @@ func NewRepository(psql postgres.PostgresDB) Repository {
 	return &repository{db: psql.DB()}
 }
 
+// Transaction runs fn in a transaction, with a repository bound to it and the
+// transaction itself for the other modules that take part in it.
+func (r *repository) Transaction(fn func(repo Repository, tx *gorm.DB) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx}, tx)
+	})
+}
+
+// Create inserts the movie with links to its existing genres and directors.
 func (r *repository) Create(req Movie) (*common.ResponseID, error) {
-	if err := r.db.Create(&req).Error; err != nil {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return create(tx, &req)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &common.ResponseID{ID: req.ID}, nil
 }
 
+func create(tx *gorm.DB, movie *Movie) error {
+	if err := tx.Omit("Genres.*", "Directors.*").Create(movie).Error; err != nil {
+		return err
+	}
+	return syncNames(tx, movie.ID)
+}
+
 func (r *repository) GetByID(req common.RequestID) (*Movie, error) {
+	byName := func(db *gorm.DB) *gorm.DB {
+		return db.Order("name ASC")
+	}
+
 	var movie Movie
-	if err := r.db.First(&movie, req.ID).Error; err != nil {
+	if err := r.db.Preload("Genres", byName).Preload("Directors", byName).First(&movie, req.ID).Error; err != nil {
 		return nil, err
 	}
 	return &movie, nil
 }
 
-func (r *repository) GetAll(req common.RequestSearch) (*MovieListResponse, error) {
-	movies := make([]MovieResponse, 0)
+// syncNames rewrites the denormalized genre and director names of the movie
+// from its links.
+func syncNames(tx *gorm.DB, id uint) error {
+	return tx.Exec(`
+		UPDATE movies AS m
+		SET genre = COALESCE((
+				SELECT string_agg(g.name, ', ' ORDER BY g.name)
+				FROM movie_genres mg
+				JOIN genres g ON g.id = mg.genre_id AND g.deleted_at IS NULL
+				WHERE mg.movie_id = m.id
+			), ''),
+			director = COALESCE((
+				SELECT string_agg(p.name, ', ' ORDER BY p.name)
+				FROM movie_directors md
+				JOIN people p ON p.id = md.person_id AND p.deleted_at IS NULL
+				WHERE md.movie_id = m.id
+			), '')
+		WHERE m.id = ?
+	`, id).Error
+}
+
+// links fills in the genres and directors of a page of movies.
+func (r *repository) links(tx *gorm.DB, movies []MovieResponse) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(movies))
+	index := make(map[uint]int, len(movies))
+	for i := range movies {
+		ids[i] = movies[i].ID
+		index[movies[i].ID] = i
+		movies[i].Genres = make([]common.ResponseRef, 0)
+		movies[i].Directors = make([]common.ResponseRef, 0)
+	}
+
+	var rows []struct {
+		MovieID uint
+		Kind    string
+		ID      uint
+		Name    string
+	}
+	if err := tx.Raw(`
+		SELECT mg.movie_id, 'genre' AS kind, g.id, g.name
+		FROM movie_genres mg
+		JOIN genres g ON g.id = mg.genre_id AND g.deleted_at IS NULL
+		WHERE mg.movie_id IN ?
+		UNION ALL
+		SELECT md.movie_id, 'director' AS kind, p.id, p.name
+		FROM movie_directors md
+		JOIN people p ON p.id = md.person_id AND p.deleted_at IS NULL
+		WHERE md.movie_id IN ?
+		ORDER BY name
+	`, ids, ids).Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		movie := &movies[index[row.MovieID]]
+		ref := common.ResponseRef{ID: row.ID, Name: row.Name}
+		if row.Kind == "genre" {
+			movie.Genres = append(movie.Genres, ref)
+		} else {
+			movie.Directors = append(movie.Directors, ref)
+		}
+	}
+
+	return nil
+}
+
+// GetAll reads the page and its total inside one read-only repeatable read
+// transaction, so both see the same snapshot.
+func (r *repository) GetAll(filter MovieFilter) (*MovieListResponse, error) {
+	response := MovieListResponse{
+		Movies: make([]MovieResponse, 0),
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var (
+			movies []MovieResponse
+			sel    selection
+			err    error
+		)
+		if filter.Search == "" {
+			sel = listSelection(filter)
+			movies, err = r.page(tx, sel, filter)
+		} else {
+			movies, sel, err = r.search(tx, filter)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := r.links(tx, movies); err != nil {
+			return err
+		}
+
+		total, estimated, err := r.count(tx, sel, filter)
+		if err != nil {
+			return err
+		}
+
+		response.Movies = movies
+		response.Total = uint64(total)
+		response.Estimated = estimated
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.CursorMode {
+		if int64(len(response.Movies)) > filter.Limit {
+			response.Movies = response.Movies[:filter.Limit]
+			response.hasMore = true
+		}
+		if filter.Cursor != nil && filter.Cursor.Backward {
+			slices.Reverse(response.Movies)
+		}
+	}
+
+	return &response, nil
+}
+
+// count counts the rows of the selection. In estimate mode it reads the row
+// estimate of the query plan instead, so the estimate sees the same live rows
+// and conditions as the exact count would. It falls back to an exact count
+// while the table has never been analyzed.
+func (r *repository) count(tx *gorm.DB, sel selection, filter MovieFilter) (int64, bool, error) {
+	if filter.EstimateCount {
+		var analyzed bool
+		if err := tx.Raw(`
+			SELECT reltuples >= 0 FROM pg_class WHERE oid = 'movies'::regclass
+		`).Scan(&analyzed).Error; err != nil {
+			return 0, false, err
+		}
+
+		if analyzed {
+			var plan string
+			if err := tx.Raw(`EXPLAIN (FORMAT JSON) `+sel.query, sel.args...).Scan(&plan).Error; err != nil {
+				return 0, false, err
+			}
+
+			var explained []struct {
+				Plan struct {
+					Rows float64 `json:"Plan Rows"`
+				} `json:"Plan"`
+			}
+			if err := json.Unmarshal([]byte(plan), &explained); err != nil {
+				return 0, false, err
+			}
+			if len(explained) > 0 {
+				return int64(explained[0].Plan.Rows), true, nil
+			}
+		}
+	}
+
 	total := int64(0)
+	if err := tx.Raw(`SELECT COUNT(*) FROM (`+sel.query+`) AS t`, sel.args...).Scan(&total).Error; err != nil {
+		return 0, false, err
+	}
+	return total, false, nil
+}
+
+const movieColumns = "id, title, year, genre, rating, rating_count, director, version, created_at, updated_at"
+
+type selection struct {
+	query string
+	args  []interface{}
+}
+
+func listSelection(filter MovieFilter) selection {
+	where := filterConditions(filter)
+
+	return selection{
+		query: `SELECT ` + movieColumns + ` FROM movies ` + where.sql(),
+		args:  where.args,
+	}
+}
+
+func searchSelection(filter MovieFilter) selection {
+	where := filterConditions(filter)
+	where.add("search_vector @@ q.query")
+
+	snippet := ""
+	if filter.Highlight {
+		snippet = `,
+			ts_headline('english', title || ' / ' || director || ' / ' || genre, q.query,
+				'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS snippet`
+	}
+
+	return selection{
+		query: `
+			SELECT ` + movieColumns + `,
+				ts_rank_cd(search_vector, q.query)::float8 AS relevance` + snippet + `
+			FROM movies, websearch_to_tsquery('english', ?) AS q(query)
+			` + where.sql(),
+		args: append([]interface{}{filter.Search}, where.args...),
+	}
+}
+
+func fuzzySelection(filter MovieFilter) selection {
+	where := filterConditions(filter)
+	where.add("(s.term <% title OR s.term <% director OR s.term <% genre)")
+
+	return selection{
+		query: `
+			SELECT ` + movieColumns + `,
+				GREATEST(
+					word_similarity(s.term, title),
+					word_similarity(s.term, director),
+					word_similarity(s.term, genre)
+				)::float8 AS relevance
+			FROM movies, (SELECT ?::text AS term) AS s
+			` + where.sql(),
+		args: append([]interface{}{filter.Search}, where.args...),
+	}
+}
+
+// page reads one page of the selection, either by offset or, in cursor mode,
+// by seeking past the cursor. Cursor mode reads one extra row to tell whether
+// another page follows.
+func (r *repository) page(tx *gorm.DB, sel selection, filter MovieFilter) ([]MovieResponse, error) {
+	movies := make([]MovieResponse, 0)
+	fields := sortFields(filter)
 
-	tr := r.db.Begin()
-	defer tr.Commit()
+	query := `SELECT * FROM (` + sel.query + `) AS t `
+	args := append([]interface{}{}, sel.args...)
 
-	if err := tr.Raw("SELECT COUNT(*) FROM movies").
-		Scan(&total).Error; err != nil {
-		tr.Rollback()
+	if filter.CursorMode {
+		backward := false
+		if filter.Cursor != nil {
+			values, err := cursorValues(*filter.Cursor, fields)
+			if err != nil {
+				return nil, err
+			}
+
+			backward = filter.Cursor.Backward
+			condition, conditionArgs := keyset(fields, values, backward)
+			query += `WHERE ` + condition + ` `
+			args = append(args, conditionArgs...)
+		}
+
+		query += orderBy(fields, backward) + ` LIMIT ?`
+		args = append(args, filter.Limit+1)
+	} else {
+		query += orderBy(fields, false) + ` LIMIT ? OFFSET ?`
+		args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+	}
+
+	if err := tx.Raw(query, args...).Scan(&movies).Error; err != nil {
 		return nil, err
 	}
+	return movies, nil
+}
+
+// exportFetchSize is how many rows Export fetches from its cursor at a time.
+const exportFetchSize = 1000
+
+// Export calls each for every movie matching the filter, in the order of
+// GetAll. The rows are read through a server-side cursor in chunks of
+// exportFetchSize, inside one read-only snapshot.
+func (r *repository) Export(filter MovieFilter, each func(MovieResponse) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		sel := listSelection(filter)
+		if filter.Search != "" {
+			sel = searchSelection(filter)
+
+			matched := false
+			if err := tx.Raw(`SELECT EXISTS (`+sel.query+`)`, sel.args...).Scan(&matched).Error; err != nil {
+				return err
+			}
+			if !matched {
+				sel = fuzzySelection(filter)
+			}
+		}
+
+		query := `DECLARE movie_export NO SCROLL CURSOR FOR SELECT * FROM (` + sel.query + `) AS t ` + orderBy(sortFields(filter), false)
+		if err := tx.Exec(query, sel.args...).Error; err != nil {
+			return err
+		}
+
+		for {
+			var movies []MovieResponse
+			if err := tx.Raw(`FETCH ` + strconv.Itoa(exportFetchSize) + ` FROM movie_export`).Scan(&movies).Error; err != nil {
+				return err
+			}
+
+			for _, movie := range movies {
+				if err := each(movie); err != nil {
+					return err
+				}
+			}
+
+			if len(movies) < exportFetchSize {
+				return tx.Exec(`CLOSE movie_export`).Error
+			}
+		}
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+// search ranks movies with full-text search over title, director and genre.
+// When the query matches nothing at all, it falls back to trigram similarity
+// so that misspelled searches still return something.
+func (r *repository) search(tx *gorm.DB, filter MovieFilter) ([]MovieResponse, selection, error) {
+	sel := searchSelection(filter)
+
+	movies, err := r.page(tx, sel, filter)
+	if err != nil {
+		return nil, sel, err
+	}
+
+	if len(movies) > 0 {
+		return movies, sel, nil
+	}
+
+	matched := false
+	if err := tx.Raw(`SELECT EXISTS (`+sel.query+`)`, sel.args...).Scan(&matched).Error; err != nil {
+		return nil, sel, err
+	}
 
-	query := `
-		SELECT id, title, year, genre, rating, director, created_at, updated_at
+	if matched {
+		return movies, sel, nil
+	}
+
+	sel = fuzzySelection(filter)
+	movies, err = r.page(tx, sel, filter)
+	return movies, sel, err
+}
+
+// Update writes the movie, bumps its version and replaces its genre and
+// director links. With ifMatch set, a movie at another version is left
+// untouched and ErrVersionMismatch is returned.
+func (r *repository) Update(req Movie, ifMatch []int64) (*MovieVersion, error) {
+	var version int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		version, err = update(tx, req, ifMatch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MovieVersion{ID: req.ID, Version: version}, nil
+}
+
+func update(tx *gorm.DB, req Movie, ifMatch []int64) (int64, error) {
+	// The new version is scanned back into updated through RETURNING.
+	var updated Movie
+	query := tx.Model(&updated).Where("id = ?", req.ID)
+	if ifMatch != nil {
+		query = query.Where("version IN ?", ifMatch)
+	}
+
+	res := query.Clauses(clause.Returning{Columns: []clause.Column{{Name: "version"}}}).Updates(map[string]interface{}{
+		"title":   req.Title,
+		"year":    req.Year,
+		"version": gorm.Expr("version + 1"),
+	})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, missingOrStale(tx, req.ID, ifMatch)
+	}
+
+	movie := Movie{Model: gorm.Model{ID: req.ID}}
+	if err := tx.Model(&movie).Association("Genres").Replace(req.Genres); err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&movie).Association("Directors").Replace(req.Directors); err != nil {
+		return 0, err
+	}
+
+	return updated.Version, syncNames(tx, req.ID)
+}
+
+// missingOrStale tells why a conditional write matched no row.
+func missingOrStale(tx *gorm.DB, id uint, ifMatch []int64) error {
+	if ifMatch == nil {
+		return gorm.ErrRecordNotFound
+	}
+
+	var exists bool
+	if err := tx.Raw(`
+		SELECT EXISTS (SELECT 1 FROM movies WHERE id = ? AND deleted_at IS NULL)
+	`, id).Scan(&exists).Error; err != nil {
+		return err
+	}
+	if !exists {
+		return gorm.ErrRecordNotFound
+	}
+	return ErrVersionMismatch
+}
+
+// FindByTitles maps the given titles to the oldest live movie with that
+// title. Import upserts on title through it.
+func (r *repository) FindByTitles(titles []string) (map[string]uint, error) {
+	return findByTitles(r.db, titles)
+}
+
+func findByTitles(tx *gorm.DB, titles []string) (map[string]uint, error) {
+	ids := make(map[string]uint, len(titles))
+	if len(titles) == 0 {
+		return ids, nil
+	}
+
+	var rows []struct {
+		ID    uint
+		Title string
+	}
+	if err := tx.Raw(`
+		SELECT DISTINCT ON (title) id, title
 		FROM movies
-	`
+		WHERE title IN ? AND deleted_at IS NULL
+		ORDER BY title, id
+	`, titles).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
 
-	filter := ""
-	if req.Search != "" {
-		filter = fmt.Sprintf("WHERE title LIKE '%%%s%%'", req.Search)
+	for _, row := range rows {
+		ids[row.Title] = row.ID
 	}
+	return ids, nil
+}
+
+// Import upserts a batch of movies on title in one transaction. Each movie
+// runs in its own savepoint, so a failing row does not undo the others.
+func (r *repository) Import(movies []Movie) ([]importResult, error) {
+	results := make([]importResult, len(movies))
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		titles := make([]string, len(movies))
+		for i, movie := range movies {
+			titles[i] = movie.Title
+		}
+		existing, err := findByTitles(tx, titles)
+		if err != nil {
+			return err
+		}
+
+		for i := range movies {
+			movie := movies[i]
+			id, found := existing[movie.Title]
 
-	query += filter
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT %d OFFSET %d", req.Limit, (req.Page-1)*req.Limit)
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				if found {
+					movie.ID = id
+					_, err := update(tx, movie, nil)
+					return err
+				}
+				return create(tx, &movie)
+			})
 
-	if err := tr.Raw(query).Scan(&movies).Error; err != nil {
-		tr.Rollback()
+			results[i] = importResult{ID: movie.ID, Created: !found, Err: err}
+			if err == nil {
+				existing[movie.Title] = movie.ID
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	return results, nil
+}
 
-	response := MovieListResponse{
-		Movies: movies,
-		Total:  uint64(total),
+// Delete soft deletes the movie. A movie already in the trash is not found.
+// With ifMatch set, a movie at another version is kept and ErrVersionMismatch
+// is returned.
+func (r *repository) Delete(req common.RequestID, ifMatch []int64) (*common.ResponseID, error) {
+	query := r.db
+	if ifMatch != nil {
+		query = query.Where("version IN ?", ifMatch)
 	}
 
+	res := query.Delete(&Movie{}, req.ID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, missingOrStale(r.db, req.ID, ifMatch)
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+func (r *repository) ListTrash(filter TrashFilter) (*TrashListResponse, error) {
+	response := TrashListResponse{
+		Movies: make([]TrashedMovieResponse, 0),
+	}
+
+	var total int64
+	if err := r.db.Raw(`SELECT COUNT(*) FROM movies WHERE deleted_at IS NOT NULL`).Scan(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Raw(`
+		SELECT `+movieColumns+`, deleted_at
+		FROM movies
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, filter.Limit, (filter.Page-1)*filter.Limit).Scan(&response.Movies).Error; err != nil {
+		return nil, err
+	}
+
+	response.Total = uint64(total)
 	return &response, nil
 }
 
-func (r *repository) Update(req Movie) (*common.ResponseID, error) {
-	var movie Movie
-	if err := r.db.Model(&movie).Where("id = ?", req.ID).Updates(req).Error; err != nil {
+// Restore brings a deleted movie back. It fails with a unique violation when
+// a live movie took its title meanwhile.
+func (r *repository) Restore(req common.RequestID) (*common.ResponseID, error) {
+	res := r.db.Exec(`
+		UPDATE movies
+		SET deleted_at = NULL, updated_at = NOW(), version = version + 1
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`, req.ID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+// TrashedBefore returns up to limit movies deleted before cutoff, oldest
+// first.
+func (r *repository) TrashedBefore(cutoff time.Time, limit int) ([]uint, error) {
+	ids := make([]uint, 0)
+	if err := r.db.Raw(`
+		SELECT id FROM movies
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		ORDER BY deleted_at, id
+		LIMIT ?
+	`, cutoff, limit).Scan(&ids).Error; err != nil {
 		return nil, err
 	}
-	return &common.ResponseID{ID: movie.ID}, nil
+	return ids, nil
 }
 
-func (r *repository) Delete(req common.RequestID) (*common.ResponseID, error) {
-	if err := r.db.Delete(&Movie{}, req.ID).Error; err != nil {
+// LockTrashed locks the movies among ids that are still deleted and returns
+// them. Run within Transaction, it keeps Restore from bringing them back
+// until the transaction ends.
+func (r *repository) LockTrashed(ids []uint) ([]uint, error) {
+	trashed := make([]uint, 0, len(ids))
+	if len(ids) == 0 {
+		return trashed, nil
+	}
+	if err := r.db.Raw(`
+		SELECT id FROM movies WHERE id IN ? AND deleted_at IS NOT NULL ORDER BY id FOR UPDATE
+	`, ids).Scan(&trashed).Error; err != nil {
 		return nil, err
 	}
-	return &common.ResponseID{ID: req.ID}, nil
+	return trashed, nil
+}
+
+// Purge removes deleted movies for good. Rows of other modules go with them
+// through ON DELETE CASCADE; the link tables are cleared here as well since
+// AutoMigrate creates their foreign keys without it. The ids come from
+// LockTrashed in the same transaction; live movies among them are still left
+// alone.
+func (r *repository) Purge(ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	for _, table := range []string{"movie_genres", "movie_directors"} {
+		if err := r.db.Exec(`DELETE FROM `+table+` WHERE movie_id IN ?`, ids).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	res := r.db.Exec(`DELETE FROM movies WHERE id IN ? AND deleted_at IS NOT NULL`, ids)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return res.RowsAffected, nil
+}
+
+// Snapshots reads the audit snapshots of the movies, deleted ones included.
+func (r *repository) Snapshots(ids []uint) (map[uint]MovieSnapshot, error) {
+	snapshots := make(map[uint]MovieSnapshot, len(ids))
+	if len(ids) == 0 {
+		return snapshots, nil
+	}
+
+	var rows []MovieSnapshot
+	if err := r.db.Raw(`
+		SELECT id, title, year, genre, director FROM movies WHERE id IN ?
+	`, ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		snapshots[row.ID] = row
+	}
+	return snapshots, nil
+}
+
+// MovieExists reports whether a movie that is not in the trash has the id.
+func (r *repository) MovieExists(id uint) (bool, error) {
+	exists := false
+	if err := r.db.Raw(`
+		SELECT EXISTS (SELECT 1 FROM movies WHERE id = ? AND deleted_at IS NULL)
+	`, id).Scan(&exists).Error; err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// NewMovieChecker exposes the repository as the common.MovieChecker the
+// modules attached to movies depend on.
+func NewMovieChecker(repo Repository) common.MovieChecker {
+	return repo
 }
@@ -1,68 +1,419 @@
 package movie
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/artwork"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/credit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/genre"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/person"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/rating"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/watchlist"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/cursor"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/jsonpatch"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
 type Service interface {
-	Create(req MovieCreateInput) (*common.ResponseID, error)
-	GetByID(req common.RequestID) (*MovieResponse, error)
-	GetAll(req common.RequestSearch) (*MovieListResponse, error)
-	Update(req MovieUpdateInput) (*common.ResponseID, error)
-	Delete(req common.RequestID) (*common.ResponseID, error)
+	Create(req MovieCreateInput, actor audit.Actor) (*common.ResponseID, error)
+	GetByID(req common.RequestID, includeCast bool) (*MovieResponse, error)
+	GetAll(filter MovieFilter) (*MovieListResponse, error)
+	DecodeCursor(token string) (*MovieCursor, error)
+	Update(req MovieUpdateInput, actor audit.Actor) (*MovieVersion, error)
+	Patch(req MoviePatchInput, actor audit.Actor) (*MovieResponse, error)
+	Delete(req common.RequestID, ifMatch []int64, actor audit.Actor) (*common.ResponseID, error)
+	Import(src io.Reader, format string, dryRun bool, actor audit.Actor) (*ImportReport, error)
+	Export(filter MovieFilter, format string, columns []string, w io.Writer) error
+	ListTrash(filter TrashFilter) (*TrashListResponse, error)
+	Restore(req common.RequestID, actor audit.Actor) (*common.ResponseID, error)
+	Purge(req common.RequestID, actor audit.Actor) (*common.ResponseID, error)
+	PurgeExpired() (int64, error)
 }
 
+var (
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrVersionMismatch = errors.New("movie was changed by someone else")
+	ErrInvalidPatch    = errors.New("invalid patch")
+)
+
+// castPreviewSize is how many top-billed actors GetByID embeds on request.
+const castPreviewSize = 10
+
 type service struct {
-	repo Repository
+	repo      Repository
+	genres    genre.Service
+	people    person.Service
+	credits   credit.Service
+	ratings   rating.Service
+	watchlist watchlist.Service
+	artwork   artwork.Service
+	audit     audit.Service
+	cfg       *config.Config
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, genres genre.Service, people person.Service, credits credit.Service, ratings rating.Service, watchlist watchlist.Service, artwork artwork.Service, audit audit.Service, cfg *config.Config) Service {
+	return &service{repo: repo, genres: genres, people: people, credits: credits, ratings: ratings, watchlist: watchlist, artwork: artwork, audit: audit, cfg: cfg}
 }
 
-func (s *service) Create(req MovieCreateInput) (*common.ResponseID, error) {
-	return s.repo.Create(Movie{
-		Title:    req.Title,
-		Year:     req.Year,
-		Genre:    req.Genre,
-		Rating:   req.Rating,
-		Director: req.Director,
+func (s *service) Create(req MovieCreateInput, actor audit.Actor) (*common.ResponseID, error) {
+	genres, err := s.genres.Resolve(withName(req.Genres, req.Genre))
+	if err != nil {
+		return nil, err
+	}
+	directors, err := s.people.Resolve(withName(req.Directors, req.Director))
+	if err != nil {
+		return nil, err
+	}
+
+	var movie *common.ResponseID
+	err = s.repo.Transaction(func(repo Repository, tx *gorm.DB) error {
+		var err error
+		movie, err = repo.Create(Movie{
+			Title:     req.Title,
+			Year:      req.Year,
+			Genres:    genres,
+			Directors: directors,
+		})
+		if err != nil {
+			return err
+		}
+
+		after, err := repo.Snapshots([]uint{movie.ID})
+		if err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionCreate, movie.ID, nil, after)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movie, nil
+}
+
+func withName(refs []common.RequestRef, name string) []common.RequestRef {
+	if name == "" {
+		return refs
+	}
+	return append(refs, common.RequestRef{Name: name})
 }
 
-func (s *service) GetByID(req common.RequestID) (*MovieResponse, error) {
+func (s *service) GetByID(req common.RequestID, includeCast bool) (*MovieResponse, error) {
 	movie, err := s.repo.GetByID(req)
 	if err != nil {
 		return nil, err
 	}
-	return &MovieResponse{
-		ID:        movie.ID,
+
+	genres := make([]common.ResponseRef, len(movie.Genres))
+	for i, g := range movie.Genres {
+		genres[i] = common.ResponseRef{ID: g.ID, Name: g.Name}
+	}
+	directors := make([]common.ResponseRef, len(movie.Directors))
+	for i, p := range movie.Directors {
+		directors[i] = common.ResponseRef{ID: p.ID, Name: p.Name}
+	}
+
+	response := MovieResponse{
+		ID:          movie.ID,
+		Title:       movie.Title,
+		Year:        movie.Year,
+		Genre:       movie.Genre,
+		Rating:      movie.Rating,
+		RatingCount: movie.RatingCount,
+		Director:    movie.Director,
+		Version:     movie.Version,
+		CreatedAt:   movie.CreatedAt,
+		UpdatedAt:   movie.UpdatedAt,
+		Genres:      genres,
+		Directors:   directors,
+	}
+
+	ratings, err := s.ratings.Summary(movie.ID)
+	if err != nil {
+		return nil, err
+	}
+	response.RatingHistogram = ratings.Histogram
+
+	images, err := s.artwork.ForMovies([]uint{movie.ID})
+	if err != nil {
+		return nil, err
+	}
+	response.Images = images[movie.ID]
+
+	if includeCast {
+		if response.Cast, err = s.credits.TopCast(movie.ID, castPreviewSize); err != nil {
+			return nil, err
+		}
+	}
+
+	return &response, nil
+}
+
+// GetAll lists movies by offset, or by keyset when filter.CursorMode is set.
+// Cursors are signed, and only accepted for the sort and filter they were
+// issued for.
+func (s *service) GetAll(filter MovieFilter) (*MovieListResponse, error) {
+	fields := sortFields(filter)
+	spec := sortSpec(fields)
+	filterHash := filterSpec(filter)
+
+	if filter.Cursor != nil {
+		if err := checkCursor(*filter.Cursor, fields, spec, filterHash); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachImages(res.Movies); err != nil {
+		return nil, err
+	}
+
+	if filter.ViewerID != 0 && len(res.Movies) > 0 {
+		if err := s.flagWatchlist(filter.ViewerID, res.Movies); err != nil {
+			return nil, err
+		}
+	}
+
+	if !filter.CursorMode || len(res.Movies) == 0 {
+		return res, nil
+	}
+
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+	hasNext := res.hasMore || backward
+	hasPrev := (res.hasMore && backward) || (filter.Cursor != nil && !backward)
+
+	if hasNext {
+		if res.NextCursor, err = s.encodeCursor(res.Movies[len(res.Movies)-1], fields, spec, filterHash, false); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if res.PrevCursor, err = s.encodeCursor(res.Movies[0], fields, spec, filterHash, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (s *service) attachImages(movies []MovieResponse) error {
+	ids := make([]uint, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	images, err := s.artwork.ForMovies(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range movies {
+		movies[i].Images = images[movies[i].ID]
+	}
+	return nil
+}
+
+func (s *service) flagWatchlist(userID uint, movies []MovieResponse) error {
+	ids := make([]uint, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	listed, err := s.watchlist.Contains(userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range movies {
+		in := listed[movies[i].ID]
+		movies[i].InWatchlist = &in
+	}
+	return nil
+}
+
+func (s *service) DecodeCursor(token string) (*MovieCursor, error) {
+	var c MovieCursor
+	if err := cursor.Decode(token, s.cfg.Auth.SecretKey, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func (s *service) encodeCursor(movie MovieResponse, fields []common.SortField, spec, filterHash string, backward bool) (string, error) {
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		values[i] = cursorValue(movie, field.Column)
+	}
+
+	return cursor.Encode(MovieCursor{Sort: spec, Filter: filterHash, Values: values, Backward: backward}, s.cfg.Auth.SecretKey)
+}
+
+func (s *service) Update(req MovieUpdateInput, actor audit.Actor) (*MovieVersion, error) {
+	genres, err := s.genres.Resolve(withName(req.Genres, req.Genre))
+	if err != nil {
+		return nil, err
+	}
+	directors, err := s.people.Resolve(withName(req.Directors, req.Director))
+	if err != nil {
+		return nil, err
+	}
+
+	var version *MovieVersion
+	err = s.repo.Transaction(func(repo Repository, tx *gorm.DB) error {
+		before, err := repo.Snapshots([]uint{req.ID})
+		if err != nil {
+			return err
+		}
+
+		version, err = repo.Update(Movie{
+			Model:     gorm.Model{ID: req.ID},
+			Title:     req.Title,
+			Year:      req.Year,
+			Genres:    genres,
+			Directors: directors,
+		}, req.IfMatch)
+		if err != nil {
+			return err
+		}
+
+		after, err := repo.Snapshots([]uint{req.ID})
+		if err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionUpdate, req.ID, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// Patch applies the patch to the current movie and writes the result, which
+// must pass the rules of Update. In the patched document a genre or director
+// whose name was edited counts as a reference by name, so the movie is
+// relinked to the genre or person with that name.
+func (s *service) Patch(req MoviePatchInput, actor audit.Actor) (*MovieResponse, error) {
+	movie, err := s.GetByID(common.RequestID{ID: req.ID}, false)
+	if err != nil {
+		return nil, err
+	}
+	if req.IfMatch != nil && !slices.Contains(req.IfMatch, movie.Version) {
+		return nil, ErrVersionMismatch
+	}
+
+	doc, err := json.Marshal(MovieUpdateInput{
 		Title:     movie.Title,
 		Year:      movie.Year,
-		Genre:     movie.Genre,
-		Rating:    movie.Rating,
-		Director:  movie.Director,
-		CreatedAt: movie.CreatedAt,
-		UpdatedAt: movie.UpdatedAt,
-	}, nil
-}
-
-func (s *service) GetAll(req common.RequestSearch) (*MovieListResponse, error) {
-	return s.repo.GetAll(req)
-}
-
-func (s *service) Update(req MovieUpdateInput) (*common.ResponseID, error) {
-	return s.repo.Update(Movie{
-		Model:    gorm.Model{ID: req.ID},
-		Title:    req.Title,
-		Year:     req.Year,
-		Genre:    req.Genre,
-		Rating:   req.Rating,
-		Director: req.Director,
+		Genres:    requestRefs(movie.Genres),
+		Directors: requestRefs(movie.Directors),
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Format {
+	case PatchFormatMerge:
+		doc, err = jsonpatch.Merge(doc, req.Patch)
+	case PatchFormatJSON:
+		doc, err = jsonpatch.Apply(doc, req.Patch)
+	default:
+		return nil, errors.Wrapf(ErrInvalidPatch, "unsupported patch format %q", req.Format)
+	}
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrInvalid) {
+			return nil, errors.Wrap(ErrInvalidPatch, err.Error())
+		}
+		return nil, err
+	}
+
+	var update MovieUpdateInput
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&update); err != nil {
+		return nil, errors.Wrap(ErrInvalidPatch, err.Error())
+	}
+	if err := common.Validate.Struct(update); err != nil {
+		return nil, errors.Wrap(ErrInvalidPatch, err.Error())
+	}
+
+	update.Genres = renamedRefs(update.Genres, movie.Genres)
+	update.Directors = renamedRefs(update.Directors, movie.Directors)
+
+	// Write against the version the patch was applied to, so a concurrent
+	// change is not silently overwritten.
+	update.ID = movie.ID
+	update.IfMatch = []int64{movie.Version}
+	if _, err := s.Update(update, actor); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(common.RequestID{ID: movie.ID}, false)
 }
 
-func (s *service) Delete(req common.RequestID) (*common.ResponseID, error) {
-	return s.repo.Delete(req)
+func requestRefs(refs []common.ResponseRef) []common.RequestRef {
+	requests := make([]common.RequestRef, len(refs))
+	for i, ref := range refs {
+		requests[i] = common.RequestRef{ID: ref.ID, Name: ref.Name}
+	}
+	return requests
+}
+
+// renamedRefs drops the ID of references whose name no longer matches the
+// one it had on the movie.
+func renamedRefs(refs []common.RequestRef, current []common.ResponseRef) []common.RequestRef {
+	names := make(map[uint]string, len(current))
+	for _, ref := range current {
+		names[ref.ID] = ref.Name
+	}
+
+	for i, ref := range refs {
+		if name, ok := names[ref.ID]; ok && ref.Name != "" && ref.Name != name {
+			refs[i].ID = 0
+		}
+	}
+	return refs
+}
+
+func (s *service) Delete(req common.RequestID, ifMatch []int64, actor audit.Actor) (*common.ResponseID, error) {
+	var movie *common.ResponseID
+	err := s.repo.Transaction(func(repo Repository, tx *gorm.DB) error {
+		before, err := repo.Snapshots([]uint{req.ID})
+		if err != nil {
+			return err
+		}
+
+		if movie, err = repo.Delete(req, ifMatch); err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionDelete, req.ID, before, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movie, nil
+}
+
+// record appends the change of a movie to the audit log within tx, taking its
+// state before and after from snapshot maps; a nil map means it did not exist.
+func (s *service) record(tx *gorm.DB, actor audit.Actor, action string, id uint, before, after map[uint]MovieSnapshot) error {
+	return s.audit.Record(tx, actor, action, audit.EntityMovie, id, snapshotOf(before, id), snapshotOf(after, id))
+}
+
+func snapshotOf(snapshots map[uint]MovieSnapshot, id uint) interface{} {
+	if snapshot, ok := snapshots[id]; ok {
+		return snapshot
+	}
+	return nil
 }
@@ -0,0 +1,183 @@
+package movie
+
+import (
+	"context"
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/artwork"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	logger "github.com/asliddinberdiev/i_tv_task/pkgs/logger/zap"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// purgeBatchSize is how many expired movies PurgeExpired removes at a time.
+const purgeBatchSize = 100
+
+func (s *service) ListTrash(filter TrashFilter) (*TrashListResponse, error) {
+	res, err := s.repo.ListTrash(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if days := s.cfg.Trash.RetentionDays; days > 0 {
+		for i := range res.Movies {
+			purgeAt := res.Movies[i].DeletedAt.AddDate(0, 0, days)
+			res.Movies[i].PurgeAt = &purgeAt
+		}
+	}
+	return res, nil
+}
+
+func (s *service) Restore(req common.RequestID, actor audit.Actor) (*common.ResponseID, error) {
+	var movie *common.ResponseID
+	err := s.repo.Transaction(func(repo Repository, tx *gorm.DB) error {
+		var err error
+		if movie, err = repo.Restore(req); err != nil {
+			return err
+		}
+
+		after, err := repo.Snapshots([]uint{req.ID})
+		if err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionRestore, req.ID, nil, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movie, nil
+}
+
+// Purge removes a deleted movie and its images for good. Live movies are not
+// found here; they have to be deleted first.
+func (s *service) Purge(req common.RequestID, actor audit.Actor) (*common.ResponseID, error) {
+	purged, err := s.purge([]uint{req.ID}, actor)
+	if err != nil {
+		return nil, err
+	}
+	if purged == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+// PurgeExpired removes the movies deleted longer ago than the retention
+// period and returns how many went.
+func (s *service) PurgeExpired() (int64, error) {
+	days := s.cfg.Trash.RetentionDays
+	if days <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	var purged int64
+	for {
+		ids, err := s.repo.TrashedBefore(cutoff, purgeBatchSize)
+		if err != nil || len(ids) == 0 {
+			return purged, err
+		}
+
+		n, err := s.purge(ids, audit.System)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+		if len(ids) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// purge removes the movies among ids that are still deleted, with their
+// images, and returns how many it removed. It locks them first in the same
+// transaction, so a concurrent Restore either wins and keeps its images or
+// waits and finds the movie gone. Image files are removed after the commit.
+func (s *service) purge(ids []uint, actor audit.Actor) (int64, error) {
+	var (
+		purged int64
+		images []artwork.Image
+	)
+	err := s.repo.Transaction(func(repo Repository, tx *gorm.DB) error {
+		trashed, err := repo.LockTrashed(ids)
+		if err != nil || len(trashed) == 0 {
+			return err
+		}
+
+		before, err := repo.Snapshots(trashed)
+		if err != nil {
+			return err
+		}
+
+		if images, err = s.artwork.DeleteForMovies(tx, trashed); err != nil {
+			return err
+		}
+		if purged, err = repo.Purge(trashed); err != nil {
+			return err
+		}
+
+		for _, id := range trashed {
+			if err := s.record(tx, actor, audit.ActionPurge, id, before, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.artwork.DeleteFiles(images)
+	return purged, nil
+}
+
+// runPurgeJob calls PurgeExpired every cfg.Trash.PurgeInterval while the app
+// runs. The first pass waits one interval, so it never races the migrations
+// run on start.
+func runPurgeJob(lc fx.Lifecycle, service Service, cfg *config.Config, log logger.Logger) {
+	if cfg.Trash.RetentionDays <= 0 || cfg.Trash.PurgeInterval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+
+				ticker := time.NewTicker(cfg.Trash.PurgeInterval)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						purged, err := service.PurgeExpired()
+						if err != nil {
+							log.Error("failed to purge expired movies", logger.Error(err))
+						} else if purged > 0 {
+							log.Info("purged expired movies", logger.Int("count", int(purged)))
+						}
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
@@ -0,0 +1,29 @@
+package person
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Person struct {
+	gorm.Model
+	Name string `gorm:"type:varchar(255);not null;index:idx_people_name,expression:lower(name)"`
+}
+
+type PersonResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PersonInput struct {
+	ID   uint   `json:"-"`
+	Name string `json:"name" validate:"required,min=2,max=255"`
+}
+
+type PersonListResponse struct {
+	People []PersonResponse `json:"people"`
+	Total  uint64           `json:"total"`
+}
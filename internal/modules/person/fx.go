@@ -0,0 +1,12 @@
+package person
+
+import "go.uber.org/fx"
+
+var Module = fx.Module(
+	"person_module",
+	fx.Provide(
+		NewRepository,
+		NewService,
+		NewHandler,
+	),
+)
@@ -0,0 +1,346 @@
+package person
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Handler interface {
+	Create(c *gin.Context)
+	GetByID(c *gin.Context)
+	GetAll(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Create a new person
+// @Description Create a new person such as a director
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param person body PersonInput true "Person"
+// @Success 201 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/people [post]
+func (h *handler) Create(c *gin.Context) {
+	var req PersonInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid request",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	person, err := h.service.Create(req)
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusCreated,
+		common.ResponseID{
+			Status:  http.StatusCreated,
+			Message: "Person created successfully",
+			ID:      person.ID,
+		},
+	)
+}
+
+// @Summary Get a person by ID
+// @Description Get a person by ID
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param id path string true "Person ID"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/people/{id} [get]
+func (h *handler) GetByID(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid person id",
+			},
+		)
+		return
+	}
+
+	person, err := h.service.GetByID(common.RequestID{ID: uint(idUint)})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Person not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "Person fetched successfully",
+			Data:    person,
+		},
+	)
+}
+
+// @Summary Get all people
+// @Description Get all people ordered by name
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(50)
+// @Param search query string false "Name contains"
+// @Success 200 {object} common.ResponseWithList
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/people [get]
+func (h *handler) GetAll(c *gin.Context) {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+
+	people, err := h.service.GetAll(common.RequestSearch{
+		Search: strings.TrimSpace(c.Query("search")),
+		Page:   page,
+		Limit:  limit,
+	})
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseWithList{
+			Status:  http.StatusOK,
+			Message: "People fetched successfully",
+			Total:   people.Total,
+			Data:    people.People,
+		},
+	)
+}
+
+// @Summary Rename a person by ID
+// @Description Rename a person by ID, linked movies follow the new name
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param id path string true "Person ID"
+// @Param person body PersonInput true "Person"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/people/{id} [put]
+func (h *handler) Update(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid person id",
+			},
+		)
+		return
+	}
+
+	var req PersonInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.ID = uint(idUint)
+	person, err := h.service.Update(req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Person not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Person updated successfully",
+			ID:      person.ID,
+		},
+	)
+}
+
+// @Summary Delete a person by ID
+// @Description Delete a person by ID, people still linked to movies cannot be deleted
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param id path string true "Person ID"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 409 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/people/{id} [delete]
+func (h *handler) Delete(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid person id",
+			},
+		)
+		return
+	}
+
+	person, err := h.service.Delete(common.RequestID{ID: uint(idUint)})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Person not found",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrInUse) {
+			c.JSON(
+				http.StatusConflict,
+				common.ResponseError{
+					Status:  http.StatusConflict,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Person deleted successfully",
+			ID:      person.ID,
+		},
+	)
+}
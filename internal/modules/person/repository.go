@@ -0,0 +1,143 @@
+package person
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(req Person) (*common.ResponseID, error)
+	GetByID(id uint) (*Person, error)
+	GetAll(req common.RequestSearch) (*PersonListResponse, error)
+	Update(req Person) (*common.ResponseID, error)
+	Delete(id uint) (*common.ResponseID, error)
+	FirstOrCreate(name string) (*Person, error)
+	CountMovies(id uint) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(psql postgres.PostgresDB) Repository {
+	return &repository{db: psql.DB()}
+}
+
+func (r *repository) Create(req Person) (*common.ResponseID, error) {
+	if err := r.db.Create(&req).Error; err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+func (r *repository) GetByID(id uint) (*Person, error) {
+	var person Person
+	if err := r.db.First(&person, id).Error; err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+func (r *repository) GetAll(req common.RequestSearch) (*PersonListResponse, error) {
+	response := PersonListResponse{
+		People: make([]PersonResponse, 0),
+	}
+
+	query := r.db.Model(&Person{})
+	if req.Search != "" {
+		query = query.Where("name ILIKE ?", "%"+req.Search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if err := query.Order("name ASC").
+		Limit(int(req.Limit)).
+		Offset(int((req.Page - 1) * req.Limit)).
+		Scan(&response.People).Error; err != nil {
+		return nil, err
+	}
+
+	response.Total = uint64(total)
+	return &response, nil
+}
+
+// Update renames the person and rewrites the denormalized director names of
+// the movies they directed, which search and sorting read.
+func (r *repository) Update(req Person) (*common.ResponseID, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Person{}).Where("id = ?", req.ID).Update("name", req.Name)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Exec(`
+			UPDATE movies AS m
+			SET director = COALESCE((
+				SELECT string_agg(p.name, ', ' ORDER BY p.name)
+				FROM movie_directors md
+				JOIN people p ON p.id = md.person_id AND p.deleted_at IS NULL
+				WHERE md.movie_id = m.id
+			), '')
+			WHERE m.id IN (SELECT movie_id FROM movie_directors WHERE person_id = ?)
+		`, req.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+func (r *repository) Delete(id uint) (*common.ResponseID, error) {
+	res := r.db.Delete(&Person{}, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &common.ResponseID{ID: id}, nil
+}
+
+// FirstOrCreate returns the oldest live person with the given name, compared
+// case-insensitively, creating one when there is none. Names are not unique,
+// since different people can share one; refer to them by ID in that case.
+func (r *repository) FirstOrCreate(name string) (*Person, error) {
+	var person Person
+	err := r.db.Where("lower(name) = lower(?)", name).Order("id ASC").First(&person).Error
+	if err == nil {
+		return &person, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	person = Person{Name: name}
+	if err := r.db.Create(&person).Error; err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// CountMovies counts the live movies the person directed or is credited on.
+func (r *repository) CountMovies(id uint) (int64, error) {
+	var count int64
+	if err := r.db.Raw(`
+		SELECT COUNT(*)
+		FROM movies m
+		WHERE m.deleted_at IS NULL AND (
+			m.id IN (SELECT movie_id FROM movie_directors WHERE person_id = ?) OR
+			m.id IN (SELECT movie_id FROM credits WHERE person_id = ? AND deleted_at IS NULL)
+		)
+	`, id, id).Scan(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
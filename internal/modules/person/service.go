@@ -0,0 +1,123 @@
+package person
+
+import (
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	Create(req PersonInput) (*common.ResponseID, error)
+	GetByID(req common.RequestID) (*PersonResponse, error)
+	GetAll(req common.RequestSearch) (*PersonListResponse, error)
+	Update(req PersonInput) (*common.ResponseID, error)
+	Delete(req common.RequestID) (*common.ResponseID, error)
+	Resolve(refs []common.RequestRef) ([]Person, error)
+	Check(refs []common.RequestRef) error
+}
+
+var (
+	ErrInUse    = errors.New("person is linked to movies")
+	ErrNotFound = errors.New("person not found")
+)
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+// NormalizeName collapses the whitespace of the name. Unlike genres, people
+// keep the case they were entered with.
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func (s *service) Create(req PersonInput) (*common.ResponseID, error) {
+	return s.repo.Create(Person{Name: NormalizeName(req.Name)})
+}
+
+func (s *service) GetByID(req common.RequestID) (*PersonResponse, error) {
+	person, err := s.repo.GetByID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &PersonResponse{
+		ID:        person.ID,
+		Name:      person.Name,
+		CreatedAt: person.CreatedAt,
+		UpdatedAt: person.UpdatedAt,
+	}, nil
+}
+
+func (s *service) GetAll(req common.RequestSearch) (*PersonListResponse, error) {
+	return s.repo.GetAll(req)
+}
+
+func (s *service) Update(req PersonInput) (*common.ResponseID, error) {
+	return s.repo.Update(Person{Model: gorm.Model{ID: req.ID}, Name: NormalizeName(req.Name)})
+}
+
+func (s *service) Delete(req common.RequestID) (*common.ResponseID, error) {
+	count, err := s.repo.CountMovies(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrInUse
+	}
+	return s.repo.Delete(req.ID)
+}
+
+// Resolve turns references into people. References by ID must exist, names
+// are created on first use. Duplicates are dropped.
+func (s *service) Resolve(refs []common.RequestRef) ([]Person, error) {
+	people := make([]Person, 0, len(refs))
+	seen := make(map[uint]bool, len(refs))
+
+	for _, ref := range refs {
+		var (
+			person *Person
+			err    error
+		)
+		if ref.ID != 0 {
+			person, err = s.repo.GetByID(ref.ID)
+			if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.Wrapf(ErrNotFound, "person %d", ref.ID)
+			}
+		} else {
+			person, err = s.repo.FirstOrCreate(NormalizeName(ref.Name))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[person.ID] {
+			seen[person.ID] = true
+			people = append(people, *person)
+		}
+	}
+
+	return people, nil
+}
+
+// Check fails like Resolve on references by ID that do not exist, but creates
+// nothing.
+func (s *service) Check(refs []common.RequestRef) error {
+	for _, ref := range refs {
+		if ref.ID == 0 {
+			continue
+		}
+		if _, err := s.repo.GetByID(ref.ID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.Wrapf(ErrNotFound, "person %d", ref.ID)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package rating
+
+import "time"
+
+const (
+	MinScore = 1
+	MaxScore = 10
+)
+
+// Rating is the score a user gave a movie, at most one per user and movie.
+type Rating struct {
+	UserID    uint `gorm:"primaryKey;autoIncrement:false"`
+	MovieID   uint `gorm:"primaryKey;autoIncrement:false;index"`
+	Score     int  `gorm:"type:smallint;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Stats is the running aggregate of the ratings of a movie. It is adjusted
+// by every rating change instead of being recomputed, and its mean and count
+// are mirrored onto the movie for listing and sorting.
+type Stats struct {
+	MovieID   uint  `gorm:"primaryKey;autoIncrement:false"`
+	Count     int64 `gorm:"not null;default:0"`
+	Sum       int64 `gorm:"not null;default:0"`
+	Score1    int64 `gorm:"column:score_1;not null;default:0"`
+	Score2    int64 `gorm:"column:score_2;not null;default:0"`
+	Score3    int64 `gorm:"column:score_3;not null;default:0"`
+	Score4    int64 `gorm:"column:score_4;not null;default:0"`
+	Score5    int64 `gorm:"column:score_5;not null;default:0"`
+	Score6    int64 `gorm:"column:score_6;not null;default:0"`
+	Score7    int64 `gorm:"column:score_7;not null;default:0"`
+	Score8    int64 `gorm:"column:score_8;not null;default:0"`
+	Score9    int64 `gorm:"column:score_9;not null;default:0"`
+	Score10   int64 `gorm:"column:score_10;not null;default:0"`
+	UpdatedAt time.Time
+}
+
+func (Stats) TableName() string {
+	return "movie_rating_stats"
+}
+
+func (s Stats) mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Sum) / float64(s.Count)
+}
+
+func (s Stats) histogram() []int64 {
+	return []int64{s.Score1, s.Score2, s.Score3, s.Score4, s.Score5, s.Score6, s.Score7, s.Score8, s.Score9, s.Score10}
+}
+
+type RatingInput struct {
+	UserID  uint `json:"-"`
+	MovieID uint `json:"-"`
+	Score   int  `json:"score" validate:"required,min=1,max=10"`
+}
+
+type RatingResponse struct {
+	MovieID   uint      `json:"movie_id"`
+	Score     int       `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SummaryResponse is the aggregate of a movie. Histogram[i] counts the
+// ratings with score i+1.
+type SummaryResponse struct {
+	MovieID   uint    `json:"movie_id"`
+	Mean      float64 `json:"mean"`
+	Count     int64   `json:"count"`
+	Histogram []int64 `json:"histogram"`
+}
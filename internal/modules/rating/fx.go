@@ -0,0 +1,12 @@
+package rating
+
+import "go.uber.org/fx"
+
+var Module = fx.Module(
+	"rating_module",
+	fx.Provide(
+		NewRepository,
+		NewService,
+		NewHandler,
+	),
+)
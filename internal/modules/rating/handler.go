@@ -0,0 +1,268 @@
+package rating
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Handler interface {
+	Get(c *gin.Context)
+	Rate(c *gin.Context)
+	Delete(c *gin.Context)
+	Summary(c *gin.Context)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Get my rating of a movie
+// @Description Get the rating the current user gave a movie
+// @Tags ratings
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/rating [get]
+func (h *handler) Get(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	rating, err := h.service.Get(c.GetUint("user_id"), uint(movieID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Rating not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "Rating fetched successfully",
+			Data:    rating,
+		},
+	)
+}
+
+// @Summary Rate a movie
+// @Description Set or change the rating of the current user for a movie, from 1 to 10
+// @Tags ratings
+// @Accept json
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param rating body RatingInput true "Rating"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/rating [put]
+func (h *handler) Rate(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	var req RatingInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.UserID = c.GetUint("user_id")
+	req.MovieID = uint(movieID)
+	summary, err := h.service.Rate(req)
+	if err != nil {
+		if errors.Is(err, ErrMovieNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "Movie rated successfully",
+			Data:    summary,
+		},
+	)
+}
+
+// @Summary Delete my rating of a movie
+// @Description Remove the rating of the current user from a movie
+// @Tags ratings
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/rating [delete]
+func (h *handler) Delete(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	summary, err := h.service.Delete(c.GetUint("user_id"), uint(movieID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Rating not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "Rating deleted successfully",
+			Data:    summary,
+		},
+	)
+}
+
+// @Summary Get the ratings of a movie
+// @Description Get the mean, count and score histogram of a movie, histogram[i] counts score i+1
+// @Tags ratings
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/movies/{id}/ratings [get]
+func (h *handler) Summary(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	summary, err := h.service.Summary(uint(movieID))
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "Ratings fetched successfully",
+			Data:    summary,
+		},
+	)
+}
@@ -0,0 +1,161 @@
+package rating
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	Get(userID, movieID uint) (*Rating, error)
+	Upsert(req Rating) (*Stats, error)
+	Delete(userID, movieID uint) (*Stats, error)
+	GetStats(movieID uint) (*Stats, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(psql postgres.PostgresDB) Repository {
+	return &repository{db: psql.DB()}
+}
+
+func (r *repository) Get(userID, movieID uint) (*Rating, error) {
+	var rating Rating
+	if err := r.db.Where("user_id = ? AND movie_id = ?", userID, movieID).First(&rating).Error; err != nil {
+		return nil, err
+	}
+	return &rating, nil
+}
+
+// Upsert stores the rating of the user and moves the aggregate by the
+// difference to their previous score.
+func (r *repository) Upsert(req Rating) (*Stats, error) {
+	var stats *Stats
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockStats(tx, req.MovieID); err != nil {
+			return err
+		}
+
+		old := 0
+		var previous Rating
+		err := tx.Where("user_id = ? AND movie_id = ?", req.UserID, req.MovieID).First(&previous).Error
+		if err == nil {
+			old = previous.Score
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "movie_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"score", "updated_at"}),
+		}).Create(&req).Error; err != nil {
+			return err
+		}
+
+		stats, err = applyStats(tx, req.MovieID, old, req.Score)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (r *repository) Delete(userID, movieID uint) (*Stats, error) {
+	var stats *Stats
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockStats(tx, movieID); err != nil {
+			return err
+		}
+
+		var previous Rating
+		if err := tx.Clauses(clause.Returning{}).
+			Where("user_id = ? AND movie_id = ?", userID, movieID).
+			Delete(&previous).Error; err != nil {
+			return err
+		}
+		if previous.Score == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var err error
+		stats, err = applyStats(tx, movieID, previous.Score, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetStats returns the aggregate of the movie, empty when it was never rated.
+func (r *repository) GetStats(movieID uint) (*Stats, error) {
+	stats := Stats{MovieID: movieID}
+	err := r.db.Where("movie_id = ?", movieID).First(&stats).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// lockStats creates the aggregate row of the movie if needed and locks it,
+// which serializes rating changes of one movie so that reading the previous
+// score and applying the difference cannot interleave.
+func lockStats(tx *gorm.DB, movieID uint) error {
+	if err := tx.Exec(`
+		INSERT INTO movie_rating_stats (movie_id, updated_at) VALUES (?, now())
+		ON CONFLICT (movie_id) DO NOTHING
+	`, movieID).Error; err != nil {
+		return err
+	}
+
+	var stats Stats
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("movie_id = ?", movieID).First(&stats).Error
+}
+
+// applyStats replaces score from with score to in the aggregate, where 0
+// stands for no rating, and mirrors the mean and count onto the movie.
+func applyStats(tx *gorm.DB, movieID uint, from, to int) (*Stats, error) {
+	count := 0
+	if to > 0 {
+		count++
+	}
+	if from > 0 {
+		count--
+	}
+
+	set := []string{"count = count + ?", "sum = sum + ?", "updated_at = now()"}
+	if from != to {
+		if from > 0 {
+			set = append(set, fmt.Sprintf("score_%d = score_%d - 1", from, from))
+		}
+		if to > 0 {
+			set = append(set, fmt.Sprintf("score_%d = score_%d + 1", to, to))
+		}
+	}
+
+	if err := tx.Exec(`
+		UPDATE movie_rating_stats SET `+strings.Join(set, ", ")+` WHERE movie_id = ?
+	`, count, to-from, movieID).Error; err != nil {
+		return nil, err
+	}
+
+	var stats Stats
+	if err := tx.Where("movie_id = ?", movieID).First(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Exec(`
+		UPDATE movies SET rating = ?, rating_count = ? WHERE id = ?
+	`, stats.mean(), stats.Count, movieID).Error; err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
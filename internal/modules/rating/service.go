@@ -0,0 +1,82 @@
+package rating
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/pkg/errors"
+)
+
+type Service interface {
+	Get(userID, movieID uint) (*RatingResponse, error)
+	Rate(req RatingInput) (*SummaryResponse, error)
+	Delete(userID, movieID uint) (*SummaryResponse, error)
+	Summary(movieID uint) (*SummaryResponse, error)
+}
+
+var ErrMovieNotFound = errors.New("movie not found")
+
+type service struct {
+	repo   Repository
+	movies common.MovieChecker
+}
+
+func NewService(repo Repository, movies common.MovieChecker) Service {
+	return &service{repo: repo, movies: movies}
+}
+
+func (s *service) Get(userID, movieID uint) (*RatingResponse, error) {
+	rating, err := s.repo.Get(userID, movieID)
+	if err != nil {
+		return nil, err
+	}
+	return &RatingResponse{
+		MovieID:   rating.MovieID,
+		Score:     rating.Score,
+		CreatedAt: rating.CreatedAt,
+		UpdatedAt: rating.UpdatedAt,
+	}, nil
+}
+
+func (s *service) Rate(req RatingInput) (*SummaryResponse, error) {
+	exists, err := s.movies.MovieExists(req.MovieID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrMovieNotFound
+	}
+
+	stats, err := s.repo.Upsert(Rating{
+		UserID:  req.UserID,
+		MovieID: req.MovieID,
+		Score:   req.Score,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary(*stats), nil
+}
+
+func (s *service) Delete(userID, movieID uint) (*SummaryResponse, error) {
+	stats, err := s.repo.Delete(userID, movieID)
+	if err != nil {
+		return nil, err
+	}
+	return summary(*stats), nil
+}
+
+func (s *service) Summary(movieID uint) (*SummaryResponse, error) {
+	stats, err := s.repo.GetStats(movieID)
+	if err != nil {
+		return nil, err
+	}
+	return summary(*stats), nil
+}
+
+func summary(stats Stats) *SummaryResponse {
+	return &SummaryResponse{
+		MovieID:   stats.MovieID,
+		Mean:      stats.mean(),
+		Count:     stats.Count,
+		Histogram: stats.histogram(),
+	}
+}
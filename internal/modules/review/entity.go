@@ -0,0 +1,98 @@
+package review
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusPublished = "published"
+	StatusRejected  = "rejected"
+)
+
+// Review is written by a user about a movie, at most one per user and movie.
+// It is only visible to others once a moderator published it; editing it
+// sends it back to moderation.
+type Review struct {
+	gorm.Model
+	MovieID        uint       `gorm:"not null;uniqueIndex:idx_reviews_movie_user,where:deleted_at IS NULL"`
+	UserID         uint       `gorm:"not null;uniqueIndex:idx_reviews_movie_user;index"`
+	Title          string     `gorm:"type:varchar(255);not null"`
+	Body           string     `gorm:"type:text;not null"`
+	Spoiler        bool       `gorm:"not null;default:false"`
+	Status         string     `gorm:"type:varchar(16);not null;default:pending;index"`
+	ModeratedBy    *uint      `gorm:"default:null"`
+	ModeratedAt    *time.Time `gorm:"default:null"`
+	ModerationNote string     `gorm:"type:varchar(500);not null;default:''"`
+}
+
+// Report flags a review as abusive. Reports stay open until a moderator
+// moderates the review.
+type Report struct {
+	ID         uint   `gorm:"primaryKey"`
+	ReviewID   uint   `gorm:"not null;uniqueIndex:idx_review_reports_review_user"`
+	UserID     uint   `gorm:"not null;uniqueIndex:idx_review_reports_review_user"`
+	Reason     string `gorm:"type:varchar(500);not null"`
+	CreatedAt  time.Time
+	ResolvedAt *time.Time `gorm:"default:null;index"`
+}
+
+func (Report) TableName() string {
+	return "review_reports"
+}
+
+type ReviewInput struct {
+	ID      uint   `json:"-"`
+	MovieID uint   `json:"-"`
+	UserID  uint   `json:"-"`
+	Title   string `json:"title" validate:"required,min=2,max=255"`
+	Body    string `json:"body" validate:"required,min=10,max=10000"`
+	Spoiler bool   `json:"spoiler"`
+}
+
+type ModerateInput struct {
+	ID          uint   `json:"-"`
+	ModeratorID uint   `json:"-"`
+	Status      string `json:"status" validate:"required,oneof=published rejected"`
+	Note        string `json:"note,omitempty" validate:"max=500"`
+}
+
+type ReportInput struct {
+	ReviewID uint   `json:"-"`
+	UserID   uint   `json:"-"`
+	Reason   string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+// ReviewFilter narrows review listings. Zero values do not filter, so public
+// listings must set Status. Moderation adds the open report counts.
+type ReviewFilter struct {
+	MovieID    uint
+	UserID     uint
+	Status     string `validate:"omitempty,oneof=pending published rejected"`
+	Reported   bool
+	Moderation bool
+	Page       int64
+	Limit      int64
+}
+
+type ReviewResponse struct {
+	ID             uint               `json:"id"`
+	MovieID        uint               `json:"movie_id"`
+	Author         common.ResponseRef `json:"author"`
+	Title          string             `json:"title"`
+	Body           string             `json:"body"`
+	Spoiler        bool               `json:"spoiler"`
+	Status         string             `json:"status"`
+	ModerationNote string             `json:"moderation_note,omitempty"`
+	OpenReports    int64              `json:"open_reports,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+type ReviewListResponse struct {
+	Reviews []ReviewResponse `json:"reviews"`
+	Total   uint64           `json:"total"`
+}
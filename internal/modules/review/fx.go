@@ -0,0 +1,12 @@
+package review
+
+import "go.uber.org/fx"
+
+var Module = fx.Module(
+	"review_module",
+	fx.Provide(
+		NewRepository,
+		NewService,
+		NewHandler,
+	),
+)
@@ -0,0 +1,685 @@
+package review
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/user"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Handler interface {
+	Create(c *gin.Context)
+	GetByID(c *gin.Context)
+	ListByMovie(c *gin.Context)
+	ListByUser(c *gin.Context)
+	ListMine(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+	Report(c *gin.Context)
+	ListForModeration(c *gin.Context)
+	Moderate(c *gin.Context)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Review a movie
+// @Description Write a review of a movie, it is published after moderation. One review per user and movie
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param review body ReviewInput true "Review"
+// @Success 201 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 409 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/movies/{id}/reviews [post]
+func (h *handler) Create(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	var req ReviewInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid request",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.MovieID = uint(movieID)
+	req.UserID = c.GetUint("user_id")
+	review, err := h.service.Create(req)
+	if err != nil {
+		if errors.Is(err, ErrMovieNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie not found",
+				},
+			)
+			return
+		}
+
+		if helper.ErrorIs(err, "duplicate") {
+			c.JSON(
+				http.StatusConflict,
+				common.ResponseError{
+					Status:  http.StatusConflict,
+					Message: "Movie already reviewed",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusCreated,
+		common.ResponseID{
+			Status:  http.StatusCreated,
+			Message: "Review submitted for moderation",
+			ID:      review.ID,
+		},
+	)
+}
+
+// @Summary Get a review by ID
+// @Description Get a published review by ID
+// @Tags reviews
+// @Produce json
+// @Param id path string true "Review ID"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/reviews/{id} [get]
+func (h *handler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid review id",
+			},
+		)
+		return
+	}
+
+	review, err := h.service.GetPublished(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Review not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "Review fetched successfully",
+			Data:    review,
+		},
+	)
+}
+
+// @Summary Get the reviews of a movie
+// @Description Get the published reviews of a movie, newest first
+// @Tags reviews
+// @Produce json
+// @Param id path string true "Movie ID"
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(10)
+// @Success 200 {object} common.ResponseWithList
+// @Failure 400 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/movies/{id}/reviews [get]
+func (h *handler) ListByMovie(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	filter := pageFilter(c)
+	filter.MovieID = uint(movieID)
+	filter.Status = StatusPublished
+	h.list(c, filter)
+}
+
+// @Summary Get the reviews of a user
+// @Description Get the published reviews written by a user, newest first
+// @Tags reviews
+// @Produce json
+// @Param id path string true "User ID"
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(10)
+// @Success 200 {object} common.ResponseWithList
+// @Failure 400 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/users/{id}/reviews [get]
+func (h *handler) ListByUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid user id",
+			},
+		)
+		return
+	}
+
+	filter := pageFilter(c)
+	filter.UserID = uint(userID)
+	filter.Status = StatusPublished
+	h.list(c, filter)
+}
+
+// @Summary Get my reviews
+// @Description Get the reviews of the current user in any moderation state
+// @Tags reviews
+// @Produce json
+// @Param status query string false "Moderation status" Enums(pending, published, rejected)
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(10)
+// @Success 200 {object} common.ResponseWithList
+// @Failure 400 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/reviews [get]
+func (h *handler) ListMine(c *gin.Context) {
+	filter := pageFilter(c)
+	filter.UserID = c.GetUint("user_id")
+	filter.Status = c.Query("status")
+	h.list(c, filter)
+}
+
+// @Summary Get reviews for moderation
+// @Description Get reviews in any moderation state with their open report counts
+// @Tags moderation
+// @Produce json
+// @Param status query string false "Moderation status" Enums(pending, published, rejected)
+// @Param reported query bool false "Only reviews with open reports"
+// @Param movie_id query int false "Movie ID"
+// @Param user_id query int false "Author ID"
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(10)
+// @Success 200 {object} common.ResponseWithList
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/moderation/reviews [get]
+func (h *handler) ListForModeration(c *gin.Context) {
+	filter := pageFilter(c)
+	filter.Status = c.Query("status")
+	filter.Reported, _ = strconv.ParseBool(c.Query("reported"))
+	filter.Moderation = true
+
+	if movieID, err := strconv.ParseUint(c.Query("movie_id"), 10, 64); err == nil {
+		filter.MovieID = uint(movieID)
+	}
+	if userID, err := strconv.ParseUint(c.Query("user_id"), 10, 64); err == nil {
+		filter.UserID = uint(userID)
+	}
+
+	h.list(c, filter)
+}
+
+func pageFilter(c *gin.Context) ReviewFilter {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	return ReviewFilter{Page: page, Limit: limit}
+}
+
+func (h *handler) list(c *gin.Context, filter ReviewFilter) {
+	if err := common.Validate.Struct(filter); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	reviews, err := h.service.GetAll(filter)
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseWithList{
+			Status:  http.StatusOK,
+			Message: "Reviews fetched successfully",
+			Total:   reviews.Total,
+			Data:    reviews.Reviews,
+		},
+	)
+}
+
+// @Summary Update my review
+// @Description Update a review of the current user, it goes back to moderation
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param review body ReviewInput true "Review"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/reviews/{id} [put]
+func (h *handler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid review id",
+			},
+		)
+		return
+	}
+
+	var req ReviewInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.ID = uint(id)
+	req.UserID = c.GetUint("user_id")
+	review, err := h.service.Update(req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Review not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Review updated and submitted for moderation",
+			ID:      review.ID,
+		},
+	)
+}
+
+// @Summary Delete a review
+// @Description Delete a review. Authors can delete their own reviews, moderators any review
+// @Tags reviews
+// @Produce json
+// @Param id path string true "Review ID"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/reviews/{id} [delete]
+func (h *handler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid review id",
+			},
+		)
+		return
+	}
+
+	moderator := false
+	if claims, ok := c.Get("claims"); ok {
+		if userClaims, ok := claims.(user.UserClaims); ok {
+			moderator = userClaims.HasRole(user.RoleModerator)
+		}
+	}
+
+	review, err := h.service.Delete(uint(id), c.GetUint("user_id"), moderator)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Review not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Review deleted successfully",
+			ID:      review.ID,
+		},
+	)
+}
+
+// @Summary Report a review
+// @Description Report a published review as abusive, once per user
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param report body ReportInput true "Report"
+// @Success 201 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 409 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/reviews/{id}/reports [post]
+func (h *handler) Report(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid review id",
+			},
+		)
+		return
+	}
+
+	var req ReportInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.ReviewID = uint(id)
+	req.UserID = c.GetUint("user_id")
+	report, err := h.service.Report(req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Review not found",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrOwnReview) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
+		if helper.ErrorIs(err, "duplicate") {
+			c.JSON(
+				http.StatusConflict,
+				common.ResponseError{
+					Status:  http.StatusConflict,
+					Message: "Review already reported",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusCreated,
+		common.ResponseID{
+			Status:  http.StatusCreated,
+			Message: "Review reported successfully",
+			ID:      report.ID,
+		},
+	)
+}
+
+// @Summary Moderate a review
+// @Description Publish or reject a review, which also resolves its open reports
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param input body ModerateInput true "Decision"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/moderation/reviews/{id} [put]
+func (h *handler) Moderate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid review id",
+			},
+		)
+		return
+	}
+
+	var req ModerateInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.ID = uint(id)
+	req.ModeratorID = c.GetUint("user_id")
+	review, err := h.service.Moderate(req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Review not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Review moderated successfully",
+			ID:      review.ID,
+		},
+	)
+}
@@ -0,0 +1,224 @@
+package review
+
+import (
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(req Review) (*common.ResponseID, error)
+	GetByID(id uint) (*ReviewResponse, error)
+	GetAll(filter ReviewFilter) (*ReviewListResponse, error)
+	Update(req Review) (*common.ResponseID, error)
+	Delete(id uint, authorID *uint) (*common.ResponseID, error)
+	Moderate(req ModerateInput) (*common.ResponseID, error)
+	CreateReport(req Report) (*common.ResponseID, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(psql postgres.PostgresDB) Repository {
+	return &repository{db: psql.DB()}
+}
+
+func (r *repository) Create(req Review) (*common.ResponseID, error) {
+	if err := r.db.Create(&req).Error; err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+const reviewColumns = `
+	r.id, r.movie_id, r.user_id AS author_id,
+	TRIM(u.first_name || ' ' || u.last_name) AS author_name,
+	r.title, r.body, r.spoiler, r.status, r.moderation_note, r.created_at, r.updated_at`
+
+type reviewRow struct {
+	ID             uint
+	MovieID        uint
+	AuthorID       uint
+	AuthorName     string
+	Title          string
+	Body           string
+	Spoiler        bool
+	Status         string
+	ModerationNote string
+	OpenReports    int64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (row reviewRow) response() ReviewResponse {
+	return ReviewResponse{
+		ID:             row.ID,
+		MovieID:        row.MovieID,
+		Author:         common.ResponseRef{ID: row.AuthorID, Name: row.AuthorName},
+		Title:          row.Title,
+		Body:           row.Body,
+		Spoiler:        row.Spoiler,
+		Status:         row.Status,
+		ModerationNote: row.ModerationNote,
+		OpenReports:    row.OpenReports,
+		CreatedAt:      row.CreatedAt,
+		UpdatedAt:      row.UpdatedAt,
+	}
+}
+
+func (r *repository) GetByID(id uint) (*ReviewResponse, error) {
+	var row reviewRow
+	res := r.db.Raw(`
+		SELECT `+reviewColumns+`
+		FROM reviews r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.id = ? AND r.deleted_at IS NULL
+	`, id).Scan(&row)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	response := row.response()
+	return &response, nil
+}
+
+// GetAll lists reviews newest first. Reviews on deleted movies or by deleted
+// users are left out.
+func (r *repository) GetAll(filter ReviewFilter) (*ReviewListResponse, error) {
+	response := ReviewListResponse{
+		Reviews: make([]ReviewResponse, 0),
+	}
+
+	clauses := []string{"r.deleted_at IS NULL", "u.deleted_at IS NULL", "m.deleted_at IS NULL"}
+	args := make([]interface{}, 0)
+	if filter.MovieID != 0 {
+		clauses = append(clauses, "r.movie_id = ?")
+		args = append(args, filter.MovieID)
+	}
+	if filter.UserID != 0 {
+		clauses = append(clauses, "r.user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.Status != "" {
+		clauses = append(clauses, "r.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Reported {
+		clauses = append(clauses, "EXISTS (SELECT 1 FROM review_reports rr WHERE rr.review_id = r.id AND rr.resolved_at IS NULL)")
+	}
+
+	from := `
+		FROM reviews r
+		JOIN users u ON u.id = r.user_id
+		JOIN movies m ON m.id = r.movie_id
+		WHERE ` + strings.Join(clauses, " AND ")
+
+	var total int64
+	if err := r.db.Raw(`SELECT COUNT(*) `+from, args...).Scan(&total).Error; err != nil {
+		return nil, err
+	}
+
+	columns := reviewColumns
+	if filter.Moderation {
+		columns += `,
+			(SELECT COUNT(*) FROM review_reports rr WHERE rr.review_id = r.id AND rr.resolved_at IS NULL) AS open_reports`
+	}
+
+	var rows []reviewRow
+	if err := r.db.Raw(`SELECT `+columns+from+`
+		ORDER BY r.created_at DESC, r.id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		response.Reviews = append(response.Reviews, row.response())
+	}
+
+	response.Total = uint64(total)
+	return &response, nil
+}
+
+// Update rewrites the review of its author and puts it back into moderation.
+func (r *repository) Update(req Review) (*common.ResponseID, error) {
+	res := r.db.Model(&Review{}).
+		Where("id = ? AND user_id = ?", req.ID, req.UserID).
+		Updates(map[string]interface{}{
+			"title":           req.Title,
+			"body":            req.Body,
+			"spoiler":         req.Spoiler,
+			"status":          StatusPending,
+			"moderated_by":    nil,
+			"moderated_at":    nil,
+			"moderation_note": "",
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+// Delete removes the review, only when authorID matches unless it is nil.
+func (r *repository) Delete(id uint, authorID *uint) (*common.ResponseID, error) {
+	query := r.db.Where("id = ?", id)
+	if authorID != nil {
+		query = query.Where("user_id = ?", *authorID)
+	}
+
+	res := query.Delete(&Review{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &common.ResponseID{ID: id}, nil
+}
+
+// Moderate sets the status of the review and resolves its open reports.
+func (r *repository) Moderate(req ModerateInput) (*common.ResponseID, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		res := tx.Model(&Review{}).
+			Where("id = ?", req.ID).
+			Updates(map[string]interface{}{
+				"status":          req.Status,
+				"moderated_by":    req.ModeratorID,
+				"moderated_at":    now,
+				"moderation_note": req.Note,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Model(&Report{}).
+			Where("review_id = ? AND resolved_at IS NULL", req.ID).
+			Update("resolved_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+func (r *repository) CreateReport(req Report) (*common.ResponseID, error) {
+	if err := r.db.Create(&req).Error; err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
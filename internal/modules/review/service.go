@@ -0,0 +1,105 @@
+package review
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	Create(req ReviewInput) (*common.ResponseID, error)
+	GetPublished(id uint) (*ReviewResponse, error)
+	GetAll(filter ReviewFilter) (*ReviewListResponse, error)
+	Update(req ReviewInput) (*common.ResponseID, error)
+	Delete(id, userID uint, moderator bool) (*common.ResponseID, error)
+	Moderate(req ModerateInput) (*common.ResponseID, error)
+	Report(req ReportInput) (*common.ResponseID, error)
+}
+
+var (
+	ErrMovieNotFound = errors.New("movie not found")
+	ErrOwnReview     = errors.New("cannot report your own review")
+)
+
+type service struct {
+	repo   Repository
+	movies common.MovieChecker
+}
+
+func NewService(repo Repository, movies common.MovieChecker) Service {
+	return &service{repo: repo, movies: movies}
+}
+
+func (s *service) Create(req ReviewInput) (*common.ResponseID, error) {
+	exists, err := s.movies.MovieExists(req.MovieID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrMovieNotFound
+	}
+
+	return s.repo.Create(Review{
+		MovieID: req.MovieID,
+		UserID:  req.UserID,
+		Title:   req.Title,
+		Body:    req.Body,
+		Spoiler: req.Spoiler,
+		Status:  StatusPending,
+	})
+}
+
+// GetPublished returns the review only once it was published.
+func (s *service) GetPublished(id uint) (*ReviewResponse, error) {
+	review, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != StatusPublished {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return review, nil
+}
+
+func (s *service) GetAll(filter ReviewFilter) (*ReviewListResponse, error) {
+	return s.repo.GetAll(filter)
+}
+
+func (s *service) Update(req ReviewInput) (*common.ResponseID, error) {
+	return s.repo.Update(Review{
+		Model:   gorm.Model{ID: req.ID},
+		UserID:  req.UserID,
+		Title:   req.Title,
+		Body:    req.Body,
+		Spoiler: req.Spoiler,
+	})
+}
+
+// Delete lets authors delete their own reviews and moderators any review.
+func (s *service) Delete(id, userID uint, moderator bool) (*common.ResponseID, error) {
+	if moderator {
+		return s.repo.Delete(id, nil)
+	}
+	return s.repo.Delete(id, &userID)
+}
+
+func (s *service) Moderate(req ModerateInput) (*common.ResponseID, error) {
+	return s.repo.Moderate(req)
+}
+
+// Report flags a published review. Each user can report a review once.
+func (s *service) Report(req ReportInput) (*common.ResponseID, error) {
+	review, err := s.GetPublished(req.ReviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.Author.ID == req.UserID {
+		return nil, ErrOwnReview
+	}
+
+	return s.repo.CreateReport(Report{
+		ReviewID: req.ReviewID,
+		UserID:   req.UserID,
+		Reason:   req.Reason,
+	})
+}
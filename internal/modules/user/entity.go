@@ -14,6 +14,36 @@ type User struct {
 	LastName  string `gorm:"type:varchar(255);not null"`
 	Email     string `gorm:"type:varchar(255);not null;unique"`
 	Password  string `gorm:"type:varchar(255);not null"`
+
+	Role string `gorm:"type:varchar(32);not null;default:viewer;index"`
+
+	EmailVerifiedAt *time.Time `gorm:"default:null"`
+
+	// PendingEmail is the address the user asked to change to. It replaces
+	// Email once a code sent to it is confirmed.
+	PendingEmail *string `gorm:"type:varchar(255);default:null"`
+}
+
+const (
+	RoleViewer    = "viewer"
+	RoleEditor    = "editor"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// roleGrants lists the roles whose permissions each role has. Editors and
+// moderators both rank above viewers and below admins, but neither has the
+// permissions of the other.
+var roleGrants = map[string][]string{
+	RoleViewer:    {RoleViewer},
+	RoleEditor:    {RoleViewer, RoleEditor},
+	RoleModerator: {RoleViewer, RoleModerator},
+	RoleAdmin:     {RoleViewer, RoleEditor, RoleModerator, RoleAdmin},
+}
+
+type AssignRoleInput struct {
+	ID   uint   `json:"-"`
+	Role string `json:"role" validate:"required,oneof=viewer editor moderator admin"`
 }
 
 type RegisterInput struct {
@@ -28,20 +58,195 @@ type LoginInput struct {
 	Password string `json:"password" validate:"required,min=6"`
 }
 
+const (
+	OtpPurposeVerifyEmail = "verify_email"
+	OtpPurposeLogin       = "login"
+	OtpPurposeChangeEmail = "change_email"
+)
+
+type OneTimeCode struct {
+	gorm.Model
+	Email      string     `gorm:"type:varchar(255);not null;index:idx_one_time_codes_email_purpose"`
+	Purpose    string     `gorm:"type:varchar(32);not null;index:idx_one_time_codes_email_purpose"`
+	CodeHash   string     `gorm:"type:varchar(64);not null"`
+	ExpiresAt  time.Time  `gorm:"not null"`
+	Attempts   int        `gorm:"not null;default:0"`
+	ConsumedAt *time.Time `gorm:"default:null"`
+}
+
+type OtpRequestInput struct {
+	Email   string `json:"email" validate:"required,email"`
+	Purpose string `json:"purpose" validate:"required,oneof=verify_email login"`
+}
+
+type OtpVerifyInput struct {
+	Email string `json:"email" validate:"required,email"`
+	Code  string `json:"code" validate:"required,numeric"`
+}
+
+// UserSnapshot is what the audit log keeps of a user. It leaves out the
+// password on purpose.
+type UserSnapshot struct {
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Email           string     `json:"email"`
+	PendingEmail    *string    `json:"pending_email"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+}
+
+func newSnapshot(user *User) *UserSnapshot {
+	return &UserSnapshot{
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		PendingEmail:    user.PendingEmail,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+	}
+}
+
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `json:"id"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Email           string     `json:"email"`
+	PendingEmail    *string    `json:"pending_email,omitempty"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ProfileInput changes the names of the current user. Omitted fields are
+// kept.
+type ProfileInput struct {
+	ID        uint    `json:"-"`
+	FirstName *string `json:"first_name,omitempty" validate:"required_without=LastName,omitempty,min=2,lowercase"`
+	LastName  *string `json:"last_name,omitempty" validate:"required_without=FirstName,omitempty,min=2,lowercase"`
+}
+
+type PasswordChangeInput struct {
+	ID              uint   `json:"-"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6,nefield=CurrentPassword"`
+}
+
+type EmailChangeInput struct {
+	ID       uint   `json:"-"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type EmailConfirmInput struct {
+	ID   uint   `json:"-"`
+	Code string `json:"code" validate:"required,numeric"`
+}
+
+// PasswordResetToken is a single-use token mailed by the forgot-password
+// flow. Only the HMAC of the token is stored.
+type PasswordResetToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"not null;index"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:"default:null"`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token" validate:"required,hexadecimal"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+// SetPasswordInput replaces the password of a user outright.
+type SetPasswordInput struct {
+	ID       uint   `json:"-"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"not null;index"`
+	TokenID   string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	FamilyID  string     `gorm:"type:varchar(64);not null;index"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time `gorm:"default:null"`
+	RevokedAt *time.Time `gorm:"default:null"`
+}
+
+type RevokedToken struct {
+	TokenID   string    `gorm:"type:varchar(64);primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+type TokenCutoff struct {
+	UserID       uint      `gorm:"primaryKey;autoIncrement:false"`
+	IssuedBefore time.Time `gorm:"not null"`
+	UpdatedAt    time.Time
+}
+
+const (
+	AttemptScopeEmail = "email"
+	AttemptScopeIP    = "ip"
+)
+
+type LoginAttempt struct {
+	Scope        string     `gorm:"type:varchar(16);primaryKey"`
+	Key          string     `gorm:"type:varchar(255);primaryKey"`
+	Failures     int        `gorm:"not null;default:0"`
+	LastFailedAt time.Time  `gorm:"not null"`
+	LockedUntil  *time.Time `gorm:"default:null"`
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// UserClaims is the JWT payload. StandardClaims.Id carries the token id (jti)
+// and Type tells access tokens apart from refresh tokens. IssuedAtMicro backs
+// up the second-precision iat, so that a token issued right after a revocation
+// cutoff is told apart from the ones revoked in the same second.
 type UserClaims struct {
-	ID uint `json:"id"`
+	ID            uint   `json:"id"`
+	Type          string `json:"type"`
+	Role          string `json:"role"`
+	IssuedAtMicro int64  `json:"iat_us,omitempty"`
 	jwt.StandardClaims
 }
 
+// issuedAt returns when the token was issued in Unix microseconds. Tokens
+// without iat_us fall back to the start of their iat second.
+func (c UserClaims) issuedAt() int64 {
+	if c.IssuedAtMicro != 0 {
+		return c.IssuedAtMicro
+	}
+	return c.IssuedAt * int64(time.Second/time.Microsecond)
+}
+
+func (c UserClaims) HasRole(required string) bool {
+	for _, role := range roleGrants[c.Role] {
+		if role == required {
+			return true
+		}
+	}
+	return false
+}
+
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
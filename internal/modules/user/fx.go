@@ -6,6 +6,7 @@ var Module = fx.Module(
 	"user_module",
 	fx.Provide(
 		NewRepository,
+		NewRevocationStore,
 		NewService,
 		NewHandler,
 	),
@@ -1,16 +1,16 @@
 package user
 
 import (
+	"math"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
-	"github.com/asliddinberdiev/i_tv_task/pkgs/auth"
 	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
 	logger "github.com/asliddinberdiev/i_tv_task/pkgs/logger/zap"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
@@ -18,6 +18,23 @@ import (
 type Handler interface {
 	Register(c *gin.Context)
 	Login(c *gin.Context)
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
+	LogoutAll(c *gin.Context)
+	Unlock(c *gin.Context)
+	RequestCode(c *gin.Context)
+	VerifyEmail(c *gin.Context)
+	LoginWithCode(c *gin.Context)
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+	AssignRole(c *gin.Context)
+
+	Me(c *gin.Context)
+	UpdateMe(c *gin.Context)
+	DeleteMe(c *gin.Context)
+	ChangePassword(c *gin.Context)
+	RequestEmailChange(c *gin.Context)
+	ConfirmEmailChange(c *gin.Context)
 }
 
 type handler struct {
@@ -30,7 +47,6 @@ func NewHandler(service Service, log logger.Logger, cfg *config.Config) Handler
 	return &handler{s: service, log: log, cfg: cfg}
 }
 
-
 // @Summary Register
 // @Description Register
 // @Tags auth
@@ -83,9 +99,10 @@ func (h *handler) Register(c *gin.Context) {
 		LastName:  input.LastName,
 		Email:     input.Email,
 		Password:  hashPassword,
+		Role:      RoleViewer,
 	}
 
-	user, err := h.s.Create(newUser)
+	user, err := h.s.Create(newUser, audit.ActorFrom(c))
 	if err != nil {
 		if helper.ErrorIs(err, "duplicate") {
 			c.JSON(
@@ -109,35 +126,13 @@ func (h *handler) Register(c *gin.Context) {
 		return
 	}
 
-	accessClaims := UserClaims{
-		ID: user.ID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Duration(h.cfg.Auth.AccessTTL) * time.Second).Unix(),
-		},
-	}
-
-	accessToken, err := auth.GenerateToken(accessClaims, h.cfg.Auth.SecretKey)
-	if err != nil {
-		h.log.Error("Login", logger.Error(err))
-		c.JSON(
-			http.StatusInternalServerError,
-			common.ResponseError{
-				Status:  http.StatusInternalServerError,
-				Message: "Failed to generate token",
-			},
-		)
-		return
+	if err := h.s.RequestCode(OtpRequestInput{Email: input.Email, Purpose: OtpPurposeVerifyEmail}); err != nil {
+		h.log.Error("Register", logger.Error(err))
 	}
 
-	refreshClaims := UserClaims{
-		ID: user.ID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Duration(h.cfg.Auth.RefreshTTL) * time.Second).Unix(),
-		},
-	}
-	refreshToken, err := auth.GenerateToken(refreshClaims, h.cfg.Auth.SecretKey)
+	res, err := h.s.GenerateTokens(user.ID)
 	if err != nil {
-		h.log.Error("Login", logger.Error(err))
+		h.log.Error("Register", logger.Error(err))
 		c.JSON(
 			http.StatusInternalServerError,
 			common.ResponseError{
@@ -148,15 +143,8 @@ func (h *handler) Register(c *gin.Context) {
 		return
 	}
 
-	res := TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ResponseID: common.ResponseID{
-			Status:  http.StatusCreated,
-			Message: "User created successfully",
-			ID:      user.ID,
-		},
-	}
+	res.Status = http.StatusCreated
+	res.Message = "User created successfully"
 
 	c.JSON(http.StatusCreated, res)
 }
@@ -169,6 +157,7 @@ func (h *handler) Register(c *gin.Context) {
 // @Param user body user.LoginInput true "User"
 // @Success 200 {object} user.TokenResponse
 // @Failure 400 {object} common.ResponseError
+// @Failure 429 {object} common.ResponseError
 // @Failure 500 {object} common.ResponseError
 // @Router /api/v1/users/login [post]
 func (h *handler) Login(c *gin.Context) {
@@ -196,10 +185,23 @@ func (h *handler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.s.GetByEmail(input.Email)
+	user, err := h.s.Authenticate(input, c.ClientIP())
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			h.log.Error("Login", logger.Error(err))
+		var locked *LockedError
+		if errors.As(err, &locked) {
+			h.log.Warn("Login", logger.Error(err), logger.String("ip", c.ClientIP()))
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			c.JSON(
+				http.StatusTooManyRequests,
+				common.ResponseError{
+					Status:  http.StatusTooManyRequests,
+					Message: "Too many failed login attempts, try again later",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrInvalidCredentials) {
 			c.JSON(
 				http.StatusBadRequest,
 				common.ResponseError{
@@ -221,66 +223,1128 @@ func (h *handler) Login(c *gin.Context) {
 		return
 	}
 
-	if !helper.PasswordCompare(user.Password, input.Password) {
+	res, err := h.s.GenerateTokens(user.ID)
+	if err != nil {
 		h.log.Error("Login", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to generate token",
+			},
+		)
+		return
+	}
+
+	res.Status = http.StatusOK
+	res.Message = "User logged in successfully"
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary Refresh
+// @Description Exchange a refresh token for a new token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body user.RefreshInput true "Refresh token"
+// @Success 200 {object} user.TokenResponse
+// @Failure 400 {object} common.ResponseError
+// @Failure 401 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/users/refresh [post]
+func (h *handler) Refresh(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("Refresh", logger.Error(err))
 		c.JSON(
 			http.StatusBadRequest,
 			common.ResponseError{
 				Status:  http.StatusBadRequest,
-				Message: "Wrong email or password",
+				Message: "Invalid body",
 			},
 		)
 		return
 	}
 
-	accessClaims := UserClaims{
-		ID: user.ID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Duration(h.cfg.Auth.AccessTTL) * time.Second).Unix(),
-		},
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
 	}
 
-	accessToken, err := auth.GenerateToken(accessClaims, h.cfg.Auth.SecretKey)
+	res, err := h.s.RefreshTokens(input.RefreshToken)
 	if err != nil {
-		h.log.Error("Login", logger.Error(err))
+		if errors.Is(err, ErrTokenReused) {
+			h.log.Warn("Refresh", logger.Error(err))
+			c.JSON(
+				http.StatusUnauthorized,
+				common.ResponseError{
+					Status:  http.StatusUnauthorized,
+					Message: "Refresh token reused, please log in again",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrInvalidToken) {
+			c.JSON(
+				http.StatusUnauthorized,
+				common.ResponseError{
+					Status:  http.StatusUnauthorized,
+					Message: "Invalid refresh token",
+				},
+			)
+			return
+		}
+
+		h.log.Error("Refresh", logger.Error(err))
 		c.JSON(
 			http.StatusInternalServerError,
 			common.ResponseError{
 				Status:  http.StatusInternalServerError,
-				Message: "Failed to generate token",
+				Message: "Failed to refresh token",
+			},
+		)
+		return
+	}
+
+	res.Status = http.StatusOK
+	res.Message = "Token refreshed successfully"
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary Logout
+// @Description Revoke the current access token and, if given, the refresh token session
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body user.LogoutInput false "Refresh token"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 401 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/users/logout [post]
+func (h *handler) Logout(c *gin.Context) {
+	claims, ok := c.Get("claims")
+	if !ok {
+		c.JSON(
+			http.StatusUnauthorized,
+			common.ResponseError{
+				Status:  http.StatusUnauthorized,
+				Message: "Unauthorized",
+			},
+		)
+		return
+	}
+
+	var input LogoutInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			h.log.Error("Logout", logger.Error(err))
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Invalid body",
+				},
+			)
+			return
+		}
+	}
+
+	userClaims := claims.(UserClaims)
+	if err := h.s.Logout(userClaims, input.RefreshToken); err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Invalid refresh token",
+				},
+			)
+			return
+		}
+
+		h.log.Error("Logout", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to logout",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "User logged out successfully",
+			ID:      userClaims.ID,
+		},
+	)
+}
+
+// @Summary Logout everywhere
+// @Description Revoke every access and refresh token issued to the current user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} common.ResponseID
+// @Failure 401 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/users/logout-all [post]
+func (h *handler) LogoutAll(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	if err := h.s.LogoutAll(userID); err != nil {
+		h.log.Error("LogoutAll", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to logout",
 			},
 		)
 		return
 	}
 
-	refreshClaims := UserClaims{
-		ID: user.ID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Duration(h.cfg.Auth.RefreshTTL) * time.Second).Unix(),
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "User logged out from all sessions successfully",
+			ID:      userID,
 		},
+	)
+}
+
+// @Summary Unlock user
+// @Description Clear the failed login counter and lock of a user
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/unlock [post]
+func (h *handler) Unlock(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid user id",
+			},
+		)
+		return
 	}
-	refreshToken, err := auth.GenerateToken(refreshClaims, h.cfg.Auth.SecretKey)
+
+	user, err := h.s.Unlock(common.RequestID{ID: uint(idUint)}, audit.ActorFrom(c))
 	if err != nil {
-		h.log.Error("Login", logger.Error(err))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "User not found",
+				},
+			)
+			return
+		}
+
+		h.log.Error("Unlock", logger.Error(err))
 		c.JSON(
 			http.StatusInternalServerError,
 			common.ResponseError{
 				Status:  http.StatusInternalServerError,
-				Message: "Failed to generate token",
+				Message: "Failed to unlock user",
 			},
 		)
 		return
 	}
 
-	res := TokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ResponseID: common.ResponseID{
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
 			Status:  http.StatusOK,
-			Message: "User logged in successfully",
+			Message: "User unlocked successfully",
 			ID:      user.ID,
 		},
+	)
+}
+
+// @Summary Request code
+// @Description Send a one-time code for email verification or passwordless login. The response is the same whether or not the email is registered
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body user.OtpRequestInput true "Code request"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ResponseError
+// @Router /api/v1/users/otp [post]
+func (h *handler) RequestCode(c *gin.Context) {
+	var input OtpRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("RequestCode", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
 	}
 
-	c.JSON(http.StatusOK, res)
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	// Like ForgotPassword, the code is mailed in the background so that the
+	// response is the same whether or not the email is registered.
+	go func() {
+		if err := h.s.RequestCode(input); err != nil {
+			h.log.Error("RequestCode", logger.Error(err))
+		}
+	}()
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "If the email is registered, a code has been sent",
+		},
+	)
+}
+
+// @Summary Verify email
+// @Description Verify the email address with a one-time code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body user.OtpVerifyInput true "Code"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/users/verify-email [post]
+func (h *handler) VerifyEmail(c *gin.Context) {
+	var input OtpVerifyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("VerifyEmail", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	user, err := h.s.VerifyEmail(input, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Invalid or expired code",
+				},
+			)
+			return
+		}
+
+		h.log.Error("VerifyEmail", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to verify email",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Email verified successfully",
+			ID:      user.ID,
+		},
+	)
+}
+
+// @Summary Login with code
+// @Description Passwordless login with a one-time code sent by email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body user.OtpVerifyInput true "Code"
+// @Success 200 {object} user.TokenResponse
+// @Failure 400 {object} common.ResponseError
+// @Failure 429 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/users/login/otp [post]
+func (h *handler) LoginWithCode(c *gin.Context) {
+	var input OtpVerifyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("LoginWithCode", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	user, err := h.s.LoginWithCode(input, audit.ActorFrom(c))
+	if err != nil {
+		var locked *LockedError
+		if errors.As(err, &locked) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			c.JSON(
+				http.StatusTooManyRequests,
+				common.ResponseError{
+					Status:  http.StatusTooManyRequests,
+					Message: "Too many failed login attempts, try again later",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrInvalidCode) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Invalid or expired code",
+				},
+			)
+			return
+		}
+
+		h.log.Error("LoginWithCode", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to login",
+			},
+		)
+		return
+	}
+
+	res, err := h.s.GenerateTokens(user.ID)
+	if err != nil {
+		h.log.Error("LoginWithCode", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to generate token",
+			},
+		)
+		return
+	}
+
+	res.Status = http.StatusOK
+	res.Message = "User logged in successfully"
+
+	c.JSON(http.StatusOK, res)
+}
+
+// @Summary Forgot password
+// @Description Mail a password reset token. The response is the same whether or not the email is registered
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body user.ForgotPasswordInput true "Email"
+// @Success 200 {object} common.Response
+// @Failure 400 {object} common.ResponseError
+// @Router /api/v1/users/password/forgot [post]
+func (h *handler) ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("ForgotPassword", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	// The token is mailed in the background, so that neither the status nor
+	// the latency of the response tells whether the email is registered.
+	go func() {
+		if err := h.s.ForgotPassword(input); err != nil {
+			h.log.Error("ForgotPassword", logger.Error(err))
+		}
+	}()
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "If the email is registered, a reset link has been sent",
+		},
+	)
+}
+
+// @Summary Reset password
+// @Description Set a new password with the token from the reset email. All sessions are signed out
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body user.ResetPasswordInput true "Token and new password"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Router /api/v1/users/password/reset [post]
+func (h *handler) ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("ResetPassword", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	user, err := h.s.ResetPassword(input, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Invalid or expired token",
+				},
+			)
+			return
+		}
+
+		h.log.Error("ResetPassword", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to reset password",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Password reset successfully",
+			ID:      user.ID,
+		},
+	)
+}
+
+// @Summary Assign role
+// @Description Change the role of a user. Their existing tokens are revoked
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param input body user.AssignRoleInput true "Role"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 403 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 409 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{id}/role [put]
+func (h *handler) AssignRole(c *gin.Context) {
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid user id",
+			},
+		)
+		return
+	}
+
+	var input AssignRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		if errors.Is(err, ErrLastAdmin) {
+			c.JSON(
+				http.StatusConflict,
+				common.ResponseError{
+					Status:  http.StatusConflict,
+					Message: "The last admin cannot be demoted",
+				},
+			)
+			return
+		}
+
+		h.log.Error("AssignRole", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	input.ID = uint(idUint)
+	user, err := h.s.AssignRole(input, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "User not found",
+				},
+			)
+			return
+		}
+
+		h.log.Error("AssignRole", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to assign role",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Role assigned successfully",
+			ID:      user.ID,
+		},
+	)
+}
+
+// @Summary Get my profile
+// @Description Get the profile of the current user
+// @Tags me
+// @Produce json
+// @Success 200 {object} common.Response{data=user.UserResponse}
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me [get]
+func (h *handler) Me(c *gin.Context) {
+	user, err := h.s.Profile(c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "User not found",
+				},
+			)
+			return
+		}
+
+		h.log.Error("Me", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to get user",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.Response{
+			Status:  http.StatusOK,
+			Message: "User fetched successfully",
+			Data:    user,
+		},
+	)
+}
+
+// @Summary Update my profile
+// @Description Change the first and/or last name of the current user
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param input body user.ProfileInput true "Names"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me [patch]
+func (h *handler) UpdateMe(c *gin.Context) {
+	var input ProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("UpdateMe", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	input.ID = c.GetUint("user_id")
+	user, err := h.s.UpdateProfile(input, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "User not found",
+				},
+			)
+			return
+		}
+
+		h.log.Error("UpdateMe", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to update user",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "User updated successfully",
+			ID:      user.ID,
+		},
+	)
+}
+
+// @Summary Delete my account
+// @Description Delete the account of the current user and revoke all of its tokens
+// @Tags me
+// @Produce json
+// @Success 200 {object} common.ResponseID
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me [delete]
+func (h *handler) DeleteMe(c *gin.Context) {
+	user, err := h.s.DeleteAccount(c.GetUint("user_id"), audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "User not found",
+				},
+			)
+			return
+		}
+
+		h.log.Error("DeleteMe", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to delete user",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "User deleted successfully",
+			ID:      user.ID,
+		},
+	)
+}
+
+// @Summary Change my password
+// @Description Set a new password after checking the current one. All sessions are signed out
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param input body user.PasswordChangeInput true "Passwords"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/password [post]
+func (h *handler) ChangePassword(c *gin.Context) {
+	var input PasswordChangeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("ChangePassword", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	input.ID = c.GetUint("user_id")
+	user, err := h.s.ChangePassword(input, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, ErrWrongPassword) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Wrong password",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "User not found",
+				},
+			)
+			return
+		}
+
+		h.log.Error("ChangePassword", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to change password",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Password changed successfully",
+			ID:      user.ID,
+		},
+	)
+}
+
+// @Summary Change my email
+// @Description Send a code to the new address. The email changes once the code is confirmed
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param input body user.EmailChangeInput true "New email and current password"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 409 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/email [post]
+func (h *handler) RequestEmailChange(c *gin.Context) {
+	var input EmailChangeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("RequestEmailChange", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	input.ID = c.GetUint("user_id")
+	user, err := h.s.RequestEmailChange(input, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, ErrWrongPassword) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Wrong password",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrEmailTaken) {
+			c.JSON(
+				http.StatusConflict,
+				common.ResponseError{
+					Status:  http.StatusConflict,
+					Message: "Email is already in use",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "User not found",
+				},
+			)
+			return
+		}
+
+		h.log.Error("RequestEmailChange", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to send code",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "A code has been sent to the new email",
+			ID:      user.ID,
+		},
+	)
+}
+
+// @Summary Confirm my new email
+// @Description Confirm the pending email with the code sent to it
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param input body user.EmailConfirmInput true "Code"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 409 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/email/verify [post]
+func (h *handler) ConfirmEmailChange(c *gin.Context) {
+	var input EmailConfirmInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		h.log.Error("ConfirmEmailChange", logger.Error(err))
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(input); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	input.ID = c.GetUint("user_id")
+	user, err := h.s.ConfirmEmailChange(input, audit.ActorFrom(c))
+	if err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: "Invalid or expired code",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrEmailTaken) {
+			c.JSON(
+				http.StatusConflict,
+				common.ResponseError{
+					Status:  http.StatusConflict,
+					Message: "Email is already in use",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "User not found",
+				},
+			)
+			return
+		}
+
+		h.log.Error("ConfirmEmailChange", logger.Error(err))
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: "Failed to change email",
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Email changed successfully",
+			ID:      user.ID,
+		},
+	)
 }
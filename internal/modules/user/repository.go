@@ -1,9 +1,12 @@
 package user
 
 import (
+	"time"
+
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
 	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository interface {
@@ -12,6 +15,42 @@ type Repository interface {
 	GetByID(req common.RequestID) (*User, error)
 	Update(user User) (*common.ResponseID, error)
 	Delete(req common.RequestID) (*common.ResponseID, error)
+	SetRole(userID uint, role string) error
+	SetPassword(userID uint, hash string) error
+	SetPendingEmail(userID uint, email string) error
+	ConfirmPendingEmail(userID uint, email string) (bool, error)
+	CountByRole(role string) (int64, error)
+	LockByRole(role string) (int64, error)
+
+	CreateRefreshToken(token RefreshToken) error
+	GetRefreshToken(tokenID string) (*RefreshToken, error)
+	UseRefreshToken(tokenID string) (bool, error)
+	RevokeRefreshTokenFamily(familyID string) error
+	RevokeUserRefreshTokens(userID uint) error
+
+	RevokeToken(token RevokedToken) error
+	IsTokenRevoked(tokenID string) (bool, error)
+	DeleteExpiredRevokedTokens() error
+	SetTokenCutoff(cutoff TokenCutoff) error
+	GetTokenCutoff(userID uint) (*TokenCutoff, error)
+
+	GetLoginAttempt(scope, key string) (*LoginAttempt, error)
+	RecordLoginFailure(scope, key string, window time.Duration) (int, error)
+	LockLogin(scope, key string, until time.Time) error
+	ResetLoginAttempts(scope, key string) error
+
+	CreateCode(code OneTimeCode) error
+	GetLatestCode(email, purpose string) (*OneTimeCode, error)
+	InvalidateCodes(email, purpose string) error
+	IncrementCodeAttempts(id uint, max int) (bool, error)
+	ConsumeCode(id uint) (bool, error)
+	MarkEmailVerified(userID uint) error
+
+	GetLatestResetToken(userID uint) (*PasswordResetToken, error)
+	CreateResetToken(token PasswordResetToken) error
+	ResetPassword(tokenHash, passwordHash string) (uint, error)
+
+	Transaction(fn func(repo Repository, tx *gorm.DB) error) error
 }
 
 type repository struct {
@@ -22,6 +61,14 @@ func NewRepository(psql postgres.PostgresDB) Repository {
 	return &repository{db: psql.DB()}
 }
 
+// Transaction runs fn in a transaction, with a repository bound to it and the
+// transaction itself for the other modules that take part in it.
+func (r *repository) Transaction(fn func(repo Repository, tx *gorm.DB) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx}, tx)
+	})
+}
+
 func (r *repository) Create(user User) (*common.ResponseID, error) {
 	if err := r.db.Create(&user).Error; err != nil {
 		return nil, err
@@ -58,3 +105,254 @@ func (r *repository) Delete(req common.RequestID) (*common.ResponseID, error) {
 	}
 	return &common.ResponseID{ID: req.ID}, nil
 }
+
+func (r *repository) SetRole(userID uint, role string) error {
+	return r.db.Model(&User{}).Where("id = ?", userID).Update("role", role).Error
+}
+
+func (r *repository) SetPassword(userID uint, hash string) error {
+	return r.db.Model(&User{}).Where("id = ?", userID).Update("password", hash).Error
+}
+
+func (r *repository) SetPendingEmail(userID uint, email string) error {
+	return r.db.Model(&User{}).Where("id = ?", userID).Update("pending_email", email).Error
+}
+
+// ConfirmPendingEmail makes email the address of the user, if it is still the
+// pending one.
+func (r *repository) ConfirmPendingEmail(userID uint, email string) (bool, error) {
+	res := r.db.Model(&User{}).
+		Where("id = ? AND pending_email = ?", userID, email).
+		Updates(map[string]interface{}{
+			"email":             email,
+			"pending_email":     nil,
+			"email_verified_at": time.Now(),
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *repository) CountByRole(role string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&User{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// LockByRole counts the users with role and locks them until the transaction
+// ends, so that two admins demoting each other cannot both succeed.
+func (r *repository) LockByRole(role string) (int64, error) {
+	var ids []uint
+	if err := r.db.Model(&User{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ?", role).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+func (r *repository) CreateRefreshToken(token RefreshToken) error {
+	return r.db.Create(&token).Error
+}
+
+func (r *repository) GetRefreshToken(tokenID string) (*RefreshToken, error) {
+	var token RefreshToken
+	if err := r.db.Where("token_id = ?", tokenID).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *repository) UseRefreshToken(tokenID string) (bool, error) {
+	res := r.db.Model(&RefreshToken{}).
+		Where("token_id = ? AND used_at IS NULL AND revoked_at IS NULL", tokenID).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *repository) RevokeRefreshTokenFamily(familyID string) error {
+	return r.db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *repository) RevokeUserRefreshTokens(userID uint) error {
+	return r.db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *repository) RevokeToken(token RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&token).Error
+}
+
+func (r *repository) IsTokenRevoked(tokenID string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *repository) DeleteExpiredRevokedTokens() error {
+	return r.db.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error
+}
+
+func (r *repository) SetTokenCutoff(cutoff TokenCutoff) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"issued_before", "updated_at"}),
+	}).Create(&cutoff).Error
+}
+
+func (r *repository) GetTokenCutoff(userID uint) (*TokenCutoff, error) {
+	var cutoff TokenCutoff
+	if err := r.db.Where("user_id = ?", userID).First(&cutoff).Error; err != nil {
+		return nil, err
+	}
+	return &cutoff, nil
+}
+
+func (r *repository) GetLoginAttempt(scope, key string) (*LoginAttempt, error) {
+	var attempt LoginAttempt
+	if err := r.db.Where("scope = ? AND key = ?", scope, key).First(&attempt).Error; err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *repository) RecordLoginFailure(scope, key string, window time.Duration) (int, error) {
+	now := time.Now()
+	failures := 0
+
+	query := `
+		INSERT INTO login_attempts (scope, key, failures, last_failed_at)
+		VALUES (?, ?, 1, ?)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failed_at < ? THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING failures
+	`
+
+	if err := r.db.Raw(query, scope, key, now, now.Add(-window)).Scan(&failures).Error; err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+func (r *repository) LockLogin(scope, key string, until time.Time) error {
+	return r.db.Model(&LoginAttempt{}).
+		Where("scope = ? AND key = ?", scope, key).
+		Updates(map[string]interface{}{"failures": 0, "locked_until": until}).Error
+}
+
+func (r *repository) ResetLoginAttempts(scope, key string) error {
+	return r.db.Where("scope = ? AND key = ?", scope, key).Delete(&LoginAttempt{}).Error
+}
+
+func (r *repository) CreateCode(code OneTimeCode) error {
+	return r.db.Create(&code).Error
+}
+
+func (r *repository) GetLatestCode(email, purpose string) (*OneTimeCode, error) {
+	var code OneTimeCode
+	if err := r.db.Where("email = ? AND purpose = ?", email, purpose).
+		Order("created_at DESC").
+		First(&code).Error; err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *repository) InvalidateCodes(email, purpose string) error {
+	return r.db.Where("email = ? AND purpose = ?", email, purpose).Delete(&OneTimeCode{}).Error
+}
+
+func (r *repository) IncrementCodeAttempts(id uint, max int) (bool, error) {
+	res := r.db.Model(&OneTimeCode{}).
+		Where("id = ? AND attempts < ?", id, max).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *repository) ConsumeCode(id uint) (bool, error) {
+	res := r.db.Model(&OneTimeCode{}).
+		Where("id = ? AND consumed_at IS NULL", id).
+		Update("consumed_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *repository) MarkEmailVerified(userID uint) error {
+	return r.db.Model(&User{}).
+		Where("id = ? AND email_verified_at IS NULL", userID).
+		Update("email_verified_at", time.Now()).Error
+}
+
+func (r *repository) GetLatestResetToken(userID uint) (*PasswordResetToken, error) {
+	var token PasswordResetToken
+	if err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CreateResetToken stores the token and drops the unused ones issued before
+// it, so only the latest mail works.
+func (r *repository) CreateResetToken(token PasswordResetToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Delete(&PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&token).Error
+	})
+}
+
+// ResetPassword uses up the token and sets the password of its user. It
+// returns gorm.ErrRecordNotFound when the token is unknown, used or expired.
+func (r *repository) ResetPassword(tokenHash, passwordHash string) (uint, error) {
+	var token PasswordResetToken
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&token).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "user_id"}}}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		res = tx.Model(&User{}).Where("id = ?", token.UserID).Update("password", passwordHash)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return token.UserID, nil
+}
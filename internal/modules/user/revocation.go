@@ -0,0 +1,182 @@
+package user
+
+import (
+	"sync"
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// RevocationStore answers whether an access token was revoked before it
+// expired. Lookups are cached in process for cfg.Auth.RevocationTTL, so a
+// revocation made by another replica becomes visible after at most that long.
+type RevocationStore interface {
+	IsRevoked(claims UserClaims) (bool, error)
+	RevokeToken(claims UserClaims) error
+	RevokeAll(userID uint) error
+}
+
+type revocationEntry struct {
+	revoked  bool
+	cachedAt time.Time
+}
+
+// cutoffEntry holds a token cutoff in Unix microseconds, the precision
+// Postgres keeps timestamps in.
+type cutoffEntry struct {
+	issuedBefore int64
+	cachedAt     time.Time
+}
+
+type revocationStore struct {
+	r         Repository
+	ttl       time.Duration
+	accessTTL time.Duration
+
+	mu        sync.RWMutex
+	tokens    map[string]revocationEntry
+	cutoffs   map[uint]cutoffEntry
+	lastSweep time.Time
+}
+
+func NewRevocationStore(repository Repository, cfg *config.Config) RevocationStore {
+	return &revocationStore{
+		r:         repository,
+		ttl:       cfg.Auth.RevocationTTL,
+		accessTTL: cfg.Auth.AccessTTL,
+		tokens:    make(map[string]revocationEntry),
+		cutoffs:   make(map[uint]cutoffEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *revocationStore) IsRevoked(claims UserClaims) (bool, error) {
+	issuedBefore, err := s.cutoff(claims.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if issuedBefore != 0 && claims.issuedAt() <= issuedBefore {
+		return true, nil
+	}
+
+	if claims.Id == "" {
+		return false, nil
+	}
+
+	return s.tokenRevoked(claims.Id)
+}
+
+func (s *revocationStore) RevokeToken(claims UserClaims) error {
+	if err := s.r.RevokeToken(RevokedToken{
+		TokenID:   claims.Id,
+		UserID:    claims.ID,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	}); err != nil {
+		return errors.Wrap(err, "failed to revoke token")
+	}
+
+	if err := s.r.DeleteExpiredRevokedTokens(); err != nil {
+		return errors.Wrap(err, "failed to delete expired revoked tokens")
+	}
+
+	s.mu.Lock()
+	s.tokens[claims.Id] = revocationEntry{revoked: true, cachedAt: time.Now()}
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *revocationStore) RevokeAll(userID uint) error {
+	now := time.Now()
+
+	if err := s.r.SetTokenCutoff(TokenCutoff{UserID: userID, IssuedBefore: now}); err != nil {
+		return errors.Wrap(err, "failed to set token cutoff")
+	}
+
+	if err := s.r.RevokeUserRefreshTokens(userID); err != nil {
+		return errors.Wrap(err, "failed to revoke refresh tokens")
+	}
+
+	s.mu.Lock()
+	s.cutoffs[userID] = cutoffEntry{issuedBefore: now.UnixMicro(), cachedAt: now}
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *revocationStore) cutoff(userID uint) (int64, error) {
+	s.mu.RLock()
+	entry, ok := s.cutoffs[userID]
+	s.mu.RUnlock()
+
+	if ok && time.Since(entry.cachedAt) < s.ttl {
+		return entry.issuedBefore, nil
+	}
+
+	var issuedBefore int64
+	cutoff, err := s.r.GetTokenCutoff(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errors.Wrap(err, "failed to get token cutoff")
+	}
+	if cutoff != nil {
+		issuedBefore = cutoff.IssuedBefore.UnixMicro()
+	}
+
+	s.mu.Lock()
+	s.sweep()
+	s.cutoffs[userID] = cutoffEntry{issuedBefore: issuedBefore, cachedAt: time.Now()}
+	s.mu.Unlock()
+
+	return issuedBefore, nil
+}
+
+func (s *revocationStore) tokenRevoked(tokenID string) (bool, error) {
+	s.mu.RLock()
+	entry, ok := s.tokens[tokenID]
+	s.mu.RUnlock()
+
+	if ok && (entry.revoked || time.Since(entry.cachedAt) < s.ttl) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := s.r.IsTokenRevoked(tokenID)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to check revoked token")
+	}
+
+	s.mu.Lock()
+	s.sweep()
+	s.tokens[tokenID] = revocationEntry{revoked: revoked, cachedAt: time.Now()}
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+// sweep drops stale cache entries. Revoked tokens are kept for as long as an
+// access token can live. Callers must hold s.mu.
+func (s *revocationStore) sweep() {
+	now := time.Now()
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+
+	for id, entry := range s.tokens {
+		keep := s.ttl
+		if entry.revoked {
+			keep = s.accessTTL
+		}
+		if now.Sub(entry.cachedAt) >= keep {
+			delete(s.tokens, id)
+		}
+	}
+
+	for id, entry := range s.cutoffs {
+		if now.Sub(entry.cachedAt) >= s.ttl {
+			delete(s.cutoffs, id)
+		}
+	}
+}
@@ -1,27 +1,104 @@
 package user
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/audit"
 	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/auth"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/mail"
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrTokenReused        = errors.New("refresh token reused")
+	ErrInvalidCredentials = errors.New("wrong email or password")
+	ErrInvalidCode        = errors.New("invalid or expired code")
+	ErrWrongPassword      = errors.New("wrong password")
+	ErrEmailTaken         = errors.New("email is already in use")
+	ErrLastAdmin          = errors.New("the last admin cannot be demoted")
 )
 
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("login locked, retry after %s", e.RetryAfter)
+}
+
 type Service interface {
-	Create(req User) (*common.ResponseID, error)
+	Create(req User, actor audit.Actor) (*common.ResponseID, error)
 	GetByEmail(email string) (*User, error)
 	GetByID(req common.RequestID) (*User, error)
-	Update(user User) (*common.ResponseID, error)
-	Delete(req common.RequestID) (*common.ResponseID, error)
+	Update(user User, actor audit.Actor) (*common.ResponseID, error)
+	Delete(req common.RequestID, actor audit.Actor) (*common.ResponseID, error)
+
+	Authenticate(input LoginInput, ip string) (*User, error)
+	Unlock(req common.RequestID, actor audit.Actor) (*common.ResponseID, error)
+
+	RequestCode(input OtpRequestInput) error
+	VerifyEmail(input OtpVerifyInput, actor audit.Actor) (*common.ResponseID, error)
+	LoginWithCode(input OtpVerifyInput, actor audit.Actor) (*User, error)
+
+	Profile(userID uint) (*UserResponse, error)
+	UpdateProfile(input ProfileInput, actor audit.Actor) (*common.ResponseID, error)
+	DeleteAccount(userID uint, actor audit.Actor) (*common.ResponseID, error)
+	ChangePassword(input PasswordChangeInput, actor audit.Actor) (*common.ResponseID, error)
+	RequestEmailChange(input EmailChangeInput, actor audit.Actor) (*common.ResponseID, error)
+	ConfirmEmailChange(input EmailConfirmInput, actor audit.Actor) (*common.ResponseID, error)
+
+	ForgotPassword(input ForgotPasswordInput) error
+	ResetPassword(input ResetPasswordInput, actor audit.Actor) (*common.ResponseID, error)
+	SetPassword(input SetPasswordInput, actor audit.Actor) (*common.ResponseID, error)
+
+	AssignRole(input AssignRoleInput, actor audit.Actor) (*common.ResponseID, error)
+	BootstrapAdmin() error
+
+	GenerateTokens(userID uint) (*TokenResponse, error)
+	RefreshTokens(refreshToken string) (*TokenResponse, error)
+	Logout(claims UserClaims, refreshToken string) error
+	LogoutAll(userID uint) error
 }
 
 type service struct {
-	r Repository
+	r           Repository
+	revocations RevocationStore
+	sender      mail.Sender
+	audit       audit.Service
+	cfg         *config.Config
 }
 
-func NewService(repository Repository) Service {
-	return &service{r: repository}
+func NewService(repository Repository, revocations RevocationStore, sender mail.Sender, audit audit.Service, cfg *config.Config) Service {
+	return &service{r: repository, revocations: revocations, sender: sender, audit: audit, cfg: cfg}
 }
 
-func (s *service) Create(req User) (*common.ResponseID, error) {
-	return s.r.Create(req)
+func (s *service) Create(req User, actor audit.Actor) (*common.ResponseID, error) {
+	var user *common.ResponseID
+	err := s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		var err error
+		if user, err = repo.Create(req); err != nil {
+			return err
+		}
+
+		after, err := snapshot(repo, user.ID)
+		if err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionCreate, user.ID, nil, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
 
 func (s *service) GetByEmail(email string) (*User, error) {
@@ -32,10 +109,963 @@ func (s *service) GetByID(req common.RequestID) (*User, error) {
 	return s.r.GetByID(req)
 }
 
-func (s *service) Update(user User) (*common.ResponseID, error) {
-	return s.r.Update(user)
+func (s *service) Update(user User, actor audit.Actor) (*common.ResponseID, error) {
+	var res *common.ResponseID
+	err := s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		before, err := snapshot(repo, user.ID)
+		if err != nil {
+			return err
+		}
+
+		if res, err = repo.Update(user); err != nil {
+			return err
+		}
+
+		after, err := snapshot(repo, user.ID)
+		if err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionUpdate, user.ID, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *service) Delete(req common.RequestID, actor audit.Actor) (*common.ResponseID, error) {
+	var res *common.ResponseID
+	err := s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		before, err := snapshot(repo, req.ID)
+		if err != nil {
+			return err
+		}
+
+		if res, err = repo.Delete(req); err != nil {
+			return err
+		}
+
+		if before == nil {
+			return nil
+		}
+		return s.record(tx, actor, audit.ActionDelete, req.ID, before, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Authenticate checks the credentials while enforcing the lockout policy:
+// failed attempts are counted per email and per client IP and either counter
+// reaching its limit locks further attempts for cfg.Auth.LockoutDuration.
+func (s *service) Authenticate(input LoginInput, ip string) (*User, error) {
+	email := strings.ToLower(input.Email)
+
+	if err := s.checkLocked(email, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := s.r.GetByEmail(input.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, s.loginFailed(email, ip, ErrInvalidCredentials)
+		}
+		return nil, err
+	}
+
+	if !helper.PasswordCompare(user.Password, input.Password) {
+		return nil, s.loginFailed(email, ip, ErrInvalidCredentials)
+	}
+
+	if err := s.loginSucceeded(email); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *service) Unlock(req common.RequestID, actor audit.Actor) (*common.ResponseID, error) {
+	user, err := s.r.GetByID(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		if err := repo.ResetLoginAttempts(AttemptScopeEmail, strings.ToLower(user.Email)); err != nil {
+			return errors.Wrap(err, "failed to reset login attempts")
+		}
+		return s.record(tx, actor, audit.ActionUnlock, user.ID, nil, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.ResponseID{ID: user.ID}, nil
+}
+
+// RequestCode sends a one-time code to the email. It reports success for
+// unknown emails, already verified emails and throttled resends alike, so the
+// response never reveals whether an account exists.
+func (s *service) RequestCode(input OtpRequestInput) error {
+	user, err := s.r.GetByEmail(input.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if input.Purpose == OtpPurposeVerifyEmail && user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return s.sendCode(user.Email, input.Purpose)
+}
+
+var codeSubjects = map[string]string{
+	OtpPurposeVerifyEmail: "Verify your email",
+	OtpPurposeLogin:       "Your login code",
+	OtpPurposeChangeEmail: "Confirm your new email",
+}
+
+// sendCode mails a new code for purpose to the address, replacing earlier
+// ones. Resends within cfg.Auth.OtpResendDelay are silently dropped.
+func (s *service) sendCode(to, purpose string) error {
+	email := strings.ToLower(to)
+
+	latest, err := s.r.GetLatestCode(email, purpose)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.Wrap(err, "failed to get latest code")
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.cfg.Auth.OtpResendDelay {
+		return nil
+	}
+
+	code, err := helper.RandomDigits(s.cfg.Auth.CodeLength)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate code")
+	}
+
+	if err := s.r.InvalidateCodes(email, purpose); err != nil {
+		return errors.Wrap(err, "failed to invalidate codes")
+	}
+
+	if err := s.r.CreateCode(OneTimeCode{
+		Email:     email,
+		Purpose:   purpose,
+		CodeHash:  helper.HMACHash(s.cfg.Auth.SecretKey, s.codeInput(email, purpose, code)),
+		ExpiresAt: time.Now().Add(s.cfg.Auth.OtpTTL),
+	}); err != nil {
+		return errors.Wrap(err, "failed to store code")
+	}
+
+	return s.sender.Send(mail.Message{
+		To:      to,
+		Subject: codeSubjects[purpose],
+		Body:    fmt.Sprintf("Your code is %s. It expires in %s.", code, s.cfg.Auth.OtpTTL),
+	})
+}
+
+func (s *service) VerifyEmail(input OtpVerifyInput, actor audit.Actor) (*common.ResponseID, error) {
+	user, err := s.r.GetByEmail(input.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCode
+		}
+		return nil, err
+	}
+
+	if err := s.verifyCode(strings.ToLower(user.Email), OtpPurposeVerifyEmail, input.Code); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyEmail(user, actor); err != nil {
+		return nil, err
+	}
+
+	return &common.ResponseID{ID: user.ID}, nil
+}
+
+// LoginWithCode signs the user in with a code sent to their email. Proving
+// access to the mailbox also verifies the email.
+func (s *service) LoginWithCode(input OtpVerifyInput, actor audit.Actor) (*User, error) {
+	email := strings.ToLower(input.Email)
+
+	if err := s.checkLocked(email, actor.IP); err != nil {
+		return nil, err
+	}
+
+	user, err := s.r.GetByEmail(input.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, s.loginFailed(email, actor.IP, ErrInvalidCode)
+		}
+		return nil, err
+	}
+
+	if err := s.verifyCode(strings.ToLower(user.Email), OtpPurposeLogin, input.Code); err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			return nil, s.loginFailed(email, actor.IP, ErrInvalidCode)
+		}
+		return nil, err
+	}
+
+	if err := s.loginSucceeded(email); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyEmail(user, actor); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// verifyEmail verifies the email of the user unless it already is.
+func (s *service) verifyEmail(user *User, actor audit.Actor) error {
+	return s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		return s.markEmailVerified(repo, tx, user, actor)
+	})
+}
+
+// markEmailVerified is verifyEmail within tx, for changes that verify the
+// email on the side.
+func (s *service) markEmailVerified(repo Repository, tx *gorm.DB, user *User, actor audit.Actor) error {
+	if err := repo.MarkEmailVerified(user.ID); err != nil {
+		return errors.Wrap(err, "failed to mark email verified")
+	}
+
+	after, err := snapshot(repo, user.ID)
+	if err != nil {
+		return err
+	}
+	return s.record(tx, actor, audit.ActionVerifyEmail, user.ID, newSnapshot(user), after)
+}
+
+func (s *service) verifyCode(email, purpose, code string) error {
+	stored, err := s.r.GetLatestCode(email, purpose)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidCode
+		}
+		return errors.Wrap(err, "failed to get code")
+	}
+
+	if stored.ConsumedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return ErrInvalidCode
+	}
+
+	allowed, err := s.r.IncrementCodeAttempts(stored.ID, s.cfg.Auth.OtpMaxAttempts)
+	if err != nil {
+		return errors.Wrap(err, "failed to count code attempt")
+	}
+	if !allowed || !helper.HMACCompare(s.cfg.Auth.SecretKey, stored.CodeHash, s.codeInput(email, purpose, code)) {
+		return ErrInvalidCode
+	}
+
+	consumed, err := s.r.ConsumeCode(stored.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to consume code")
+	}
+	if !consumed {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+func (s *service) codeInput(email, purpose, code string) string {
+	return purpose + ":" + email + ":" + code
+}
+
+// resetTokenBytes is the entropy of password reset tokens, sent hex encoded.
+const resetTokenBytes = 32
+
+// ForgotPassword mails a password reset token. Unknown emails and throttled
+// resends are silently dropped. It does the lookup and the mailing in one go,
+// so callers run it off the request path to keep both out of the response.
+func (s *service) ForgotPassword(input ForgotPasswordInput) error {
+	user, err := s.r.GetByEmail(input.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	latest, err := s.r.GetLatestResetToken(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.Wrap(err, "failed to get latest reset token")
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.cfg.Auth.OtpResendDelay {
+		return nil
+	}
+
+	token, err := helper.RandomHex(resetTokenBytes)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate reset token")
+	}
+
+	if err := s.r.CreateResetToken(PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: helper.HMACHash(s.cfg.Auth.SecretKey, s.resetInput(token)),
+		ExpiresAt: time.Now().Add(s.cfg.Auth.PasswordResetTTL),
+	}); err != nil {
+		return errors.Wrap(err, "failed to store reset token")
+	}
+
+	body := fmt.Sprintf("Your password reset token is %s.", token)
+	if s.cfg.Auth.PasswordResetURL != "" {
+		body = fmt.Sprintf("Reset your password at %s?token=%s.", s.cfg.Auth.PasswordResetURL, token)
+	}
+
+	return s.sender.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("%s It expires in %s. If you did not ask for it, ignore this email.",
+			body, s.cfg.Auth.PasswordResetTTL),
+	})
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. The
+// token works once, and every session of the user is signed out.
+func (s *service) ResetPassword(input ResetPasswordInput, actor audit.Actor) (*common.ResponseID, error) {
+	hashPassword, err := helper.PasswordHash(input.Password)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash password")
+	}
+
+	var user *User
+	err = s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		userID, err := repo.ResetPassword(helper.HMACHash(s.cfg.Auth.SecretKey, s.resetInput(input.Token)), hashPassword)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidToken
+			}
+			return errors.Wrap(err, "failed to reset password")
+		}
+
+		if err := s.record(tx, actor, audit.ActionResetPassword, userID, nil, nil); err != nil {
+			return err
+		}
+
+		if user, err = repo.GetByID(common.RequestID{ID: userID}); err != nil {
+			return err
+		}
+
+		// Receiving the token proves access to the mailbox.
+		return s.markEmailVerified(repo, tx, user, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.revocations.RevokeAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.r.ResetLoginAttempts(AttemptScopeEmail, strings.ToLower(user.Email)); err != nil {
+		return nil, errors.Wrap(err, "failed to reset login attempts")
+	}
+
+	return &common.ResponseID{ID: user.ID}, nil
+}
+
+// SetPassword replaces the password of a user without asking for the current
+// one, for operators. Like a reset, it signs out every session and lifts a
+// login lockout.
+func (s *service) SetPassword(input SetPasswordInput, actor audit.Actor) (*common.ResponseID, error) {
+	user, err := s.r.GetByID(common.RequestID{ID: input.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	hashPassword, err := helper.PasswordHash(input.Password)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash password")
+	}
+
+	err = s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		if err := repo.SetPassword(user.ID, hashPassword); err != nil {
+			return errors.Wrap(err, "failed to set password")
+		}
+		return s.record(tx, actor, audit.ActionResetPassword, user.ID, nil, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.revocations.RevokeAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.r.ResetLoginAttempts(AttemptScopeEmail, strings.ToLower(user.Email)); err != nil {
+		return nil, errors.Wrap(err, "failed to reset login attempts")
+	}
+
+	return &common.ResponseID{ID: user.ID}, nil
+}
+
+func (s *service) resetInput(token string) string {
+	return "password_reset:" + strings.ToLower(token)
 }
 
-func (s *service) Delete(req common.RequestID) (*common.ResponseID, error) {
-	return s.r.Delete(req)
+func (s *service) Profile(userID uint) (*UserResponse, error) {
+	user, err := s.r.GetByID(common.RequestID{ID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserResponse{
+		ID:              user.ID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		PendingEmail:    user.PendingEmail,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}, nil
+}
+
+func (s *service) UpdateProfile(input ProfileInput, actor audit.Actor) (*common.ResponseID, error) {
+	var res *common.ResponseID
+	err := s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		user, err := repo.GetByID(common.RequestID{ID: input.ID})
+		if err != nil {
+			return err
+		}
+		before := newSnapshot(user)
+
+		if input.FirstName != nil {
+			user.FirstName = *input.FirstName
+		}
+		if input.LastName != nil {
+			user.LastName = *input.LastName
+		}
+
+		res, err = repo.Update(User{
+			Model:     gorm.Model{ID: user.ID},
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+		})
+		if err != nil {
+			return err
+		}
+
+		after, err := snapshot(repo, user.ID)
+		if err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionUpdate, user.ID, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// DeleteAccount soft-deletes the user and revokes all of their tokens.
+func (s *service) DeleteAccount(userID uint, actor audit.Actor) (*common.ResponseID, error) {
+	err := s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		user, err := repo.GetByID(common.RequestID{ID: userID})
+		if err != nil {
+			return err
+		}
+
+		if _, err := repo.Delete(common.RequestID{ID: userID}); err != nil {
+			return errors.Wrap(err, "failed to delete user")
+		}
+		return s.record(tx, actor, audit.ActionDelete, userID, newSnapshot(user), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.revocations.RevokeAll(userID); err != nil {
+		return nil, err
+	}
+
+	return &common.ResponseID{ID: userID}, nil
+}
+
+// ChangePassword sets a new password after checking the current one. Every
+// session is signed out, so the new password is needed to log in again.
+func (s *service) ChangePassword(input PasswordChangeInput, actor audit.Actor) (*common.ResponseID, error) {
+	user, err := s.r.GetByID(common.RequestID{ID: input.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	if !helper.PasswordCompare(user.Password, input.CurrentPassword) {
+		return nil, ErrWrongPassword
+	}
+
+	hashPassword, err := helper.PasswordHash(input.NewPassword)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash password")
+	}
+
+	err = s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		if err := repo.SetPassword(user.ID, hashPassword); err != nil {
+			return errors.Wrap(err, "failed to set password")
+		}
+		return s.record(tx, actor, audit.ActionChangePassword, user.ID, nil, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.revocations.RevokeAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	return &common.ResponseID{ID: user.ID}, nil
+}
+
+// RequestEmailChange keeps the new address as pending and sends it a code.
+// The email of the account only changes once ConfirmEmailChange gets the code.
+func (s *service) RequestEmailChange(input EmailChangeInput, actor audit.Actor) (*common.ResponseID, error) {
+	user, err := s.r.GetByID(common.RequestID{ID: input.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	if !helper.PasswordCompare(user.Password, input.Password) {
+		return nil, ErrWrongPassword
+	}
+
+	email := strings.ToLower(input.Email)
+	if email == strings.ToLower(user.Email) {
+		return nil, ErrEmailTaken
+	}
+
+	if _, err := s.r.GetByEmail(email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	err = s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		if err := repo.SetPendingEmail(user.ID, email); err != nil {
+			return errors.Wrap(err, "failed to set pending email")
+		}
+
+		after, err := snapshot(repo, user.ID)
+		if err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionRequestEmailChange, user.ID, newSnapshot(user), after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sendCode(email, OtpPurposeChangeEmail); err != nil {
+		return nil, err
+	}
+
+	return &common.ResponseID{ID: user.ID}, nil
+}
+
+func (s *service) ConfirmEmailChange(input EmailConfirmInput, actor audit.Actor) (*common.ResponseID, error) {
+	user, err := s.r.GetByID(common.RequestID{ID: input.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	if user.PendingEmail == nil {
+		return nil, ErrInvalidCode
+	}
+	email := *user.PendingEmail
+
+	if err := s.verifyCode(email, OtpPurposeChangeEmail, input.Code); err != nil {
+		return nil, err
+	}
+
+	err = s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		changed, err := repo.ConfirmPendingEmail(user.ID, email)
+		if err != nil {
+			if helper.ErrorIs(err, "duplicate") {
+				return ErrEmailTaken
+			}
+			return errors.Wrap(err, "failed to change email")
+		}
+		if !changed {
+			return ErrInvalidCode
+		}
+
+		after, err := snapshot(repo, user.ID)
+		if err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionChangeEmail, user.ID, newSnapshot(user), after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.ResponseID{ID: user.ID}, nil
+}
+
+// AssignRole changes the role of a user and revokes their tokens, so the new
+// role takes effect on the next login instead of when the tokens expire.
+func (s *service) AssignRole(input AssignRoleInput, actor audit.Actor) (*common.ResponseID, error) {
+	err := s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+		before, err := snapshot(repo, input.ID)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return gorm.ErrRecordNotFound
+		}
+
+		if before.Role == RoleAdmin && input.Role != RoleAdmin {
+			admins, err := repo.LockByRole(RoleAdmin)
+			if err != nil {
+				return errors.Wrap(err, "failed to count admins")
+			}
+			if admins <= 1 {
+				return ErrLastAdmin
+			}
+		}
+
+		if err := repo.SetRole(input.ID, input.Role); err != nil {
+			return errors.Wrap(err, "failed to set role")
+		}
+
+		after, err := snapshot(repo, input.ID)
+		if err != nil {
+			return err
+		}
+		return s.record(tx, actor, audit.ActionAssignRole, input.ID, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.revocations.RevokeAll(input.ID); err != nil {
+		return nil, err
+	}
+
+	return &common.ResponseID{ID: input.ID}, nil
+}
+
+// BootstrapAdmin makes sure an admin exists. When there is none, the user with
+// cfg.Auth.AdminEmail is promoted, or created with cfg.Auth.AdminPassword.
+func (s *service) BootstrapAdmin() error {
+	if s.cfg.Auth.AdminEmail == "" {
+		return nil
+	}
+
+	count, err := s.r.CountByRole(RoleAdmin)
+	if err != nil {
+		return errors.Wrap(err, "failed to count admins")
+	}
+	if count > 0 {
+		return nil
+	}
+
+	user, err := s.r.GetByEmail(s.cfg.Auth.AdminEmail)
+	if err == nil {
+		return s.r.Transaction(func(repo Repository, tx *gorm.DB) error {
+			if err := repo.SetRole(user.ID, RoleAdmin); err != nil {
+				return err
+			}
+
+			after, err := snapshot(repo, user.ID)
+			if err != nil {
+				return err
+			}
+			return s.record(tx, audit.System, audit.ActionAssignRole, user.ID, newSnapshot(user), after)
+		})
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.Wrap(err, "failed to get admin user")
+	}
+
+	if s.cfg.Auth.AdminPassword == "" {
+		return errors.New("admin password is required to create the admin user")
+	}
+
+	hashPassword, err := helper.PasswordHash(s.cfg.Auth.AdminPassword)
+	if err != nil {
+		return errors.Wrap(err, "failed to hash admin password")
+	}
+
+	now := time.Now()
+	if _, err := s.Create(User{
+		FirstName:       "admin",
+		LastName:        "admin",
+		Email:           s.cfg.Auth.AdminEmail,
+		Password:        hashPassword,
+		Role:            RoleAdmin,
+		EmailVerifiedAt: &now,
+	}, audit.System); err != nil {
+		return errors.Wrap(err, "failed to create admin user")
+	}
+
+	return nil
+}
+
+// snapshot reads what the audit log keeps of a user, or nil when there is no
+// such user.
+func snapshot(repo Repository, userID uint) (*UserSnapshot, error) {
+	user, err := repo.GetByID(common.RequestID{ID: userID})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return newSnapshot(user), nil
+}
+
+// record appends the change of a user to the audit log within tx; a nil
+// snapshot means the user did not exist on that side, or that the change has
+// no fields to show, like a new password.
+func (s *service) record(tx *gorm.DB, actor audit.Actor, action string, userID uint, before, after *UserSnapshot) error {
+	var b, a interface{}
+	if before != nil {
+		b = before
+	}
+	if after != nil {
+		a = after
+	}
+	return s.audit.Record(tx, actor, action, audit.EntityUser, userID, b, a)
+}
+
+func (s *service) checkLocked(email, ip string) error {
+	now := time.Now()
+
+	for scope, key := range map[string]string{AttemptScopeEmail: email, AttemptScopeIP: ip} {
+		if key == "" {
+			continue
+		}
+
+		attempt, err := s.r.GetLoginAttempt(scope, key)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return errors.Wrap(err, "failed to get login attempts")
+		}
+
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			return &LockedError{RetryAfter: attempt.LockedUntil.Sub(now)}
+		}
+	}
+
+	return nil
+}
+
+// loginFailed counts a failed login against the email and the client IP and
+// returns a LockedError once either reaches its limit, failure otherwise.
+func (s *service) loginFailed(email, ip string, failure error) error {
+	limits := []struct {
+		scope string
+		key   string
+		max   int
+	}{
+		{scope: AttemptScopeEmail, key: email, max: s.cfg.Auth.MaxLoginAttempts},
+		{scope: AttemptScopeIP, key: ip, max: s.cfg.Auth.MaxIPAttempts},
+	}
+
+	var locked error
+	for _, limit := range limits {
+		if limit.key == "" || limit.max <= 0 {
+			continue
+		}
+
+		failures, err := s.r.RecordLoginFailure(limit.scope, limit.key, s.cfg.Auth.LockoutDuration)
+		if err != nil {
+			return errors.Wrap(err, "failed to record login failure")
+		}
+
+		if failures >= limit.max {
+			if err := s.r.LockLogin(limit.scope, limit.key, time.Now().Add(s.cfg.Auth.LockoutDuration)); err != nil {
+				return errors.Wrap(err, "failed to lock login")
+			}
+			locked = &LockedError{RetryAfter: s.cfg.Auth.LockoutDuration}
+		}
+	}
+
+	if locked != nil {
+		return locked
+	}
+	return failure
+}
+
+// loginSucceeded clears the failure counter of the email. The client IP keeps
+// its count until the window runs out, or logging into one account between
+// guesses would reset the limit on guessing others.
+func (s *service) loginSucceeded(email string) error {
+	if err := s.r.ResetLoginAttempts(AttemptScopeEmail, email); err != nil {
+		return errors.Wrap(err, "failed to reset login attempts")
+	}
+	return nil
+}
+
+func (s *service) GenerateTokens(userID uint) (*TokenResponse, error) {
+	familyID, err := helper.RandomHex(16)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate token family")
+	}
+
+	return s.issueTokens(userID, familyID)
+}
+
+// RefreshTokens exchanges a refresh token for a new pair and marks the old one
+// as used. Presenting an already used token revokes its whole family, since
+// it means the token has leaked.
+func (s *service) RefreshTokens(refreshToken string) (*TokenResponse, error) {
+	var claims UserClaims
+	if err := auth.ParseToken(refreshToken, s.cfg.Auth.SecretKey, &claims); err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+
+	if claims.Type != TokenTypeRefresh || claims.Id == "" {
+		return nil, ErrInvalidToken
+	}
+
+	stored, err := s.r.GetRefreshToken(claims.Id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if stored.UserID != claims.ID || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, ErrInvalidToken
+	}
+
+	used, err := s.r.UseRefreshToken(stored.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !used {
+		if err := s.r.RevokeRefreshTokenFamily(stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+
+	return s.issueTokens(stored.UserID, stored.FamilyID)
+}
+
+// Logout revokes the presented access token and, when given, the session the
+// refresh token belongs to.
+func (s *service) Logout(claims UserClaims, refreshToken string) error {
+	if err := s.revocations.RevokeToken(claims); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	var refreshClaims UserClaims
+	if err := auth.ParseToken(refreshToken, s.cfg.Auth.SecretKey, &refreshClaims); err != nil {
+		return errors.Wrap(ErrInvalidToken, err.Error())
+	}
+
+	if refreshClaims.Type != TokenTypeRefresh || refreshClaims.ID != claims.ID {
+		return ErrInvalidToken
+	}
+
+	stored, err := s.r.GetRefreshToken(refreshClaims.Id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	return s.r.RevokeRefreshTokenFamily(stored.FamilyID)
+}
+
+func (s *service) LogoutAll(userID uint) error {
+	return s.revocations.RevokeAll(userID)
+}
+
+func (s *service) issueTokens(userID uint, familyID string) (*TokenResponse, error) {
+	user, err := s.r.GetByID(common.RequestID{ID: userID})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+
+	accessID, err := helper.RandomHex(16)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate token id")
+	}
+
+	accessClaims := UserClaims{
+		ID:            userID,
+		Type:          TokenTypeAccess,
+		Role:          user.Role,
+		IssuedAtMicro: now.UnixMicro(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        accessID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.cfg.Auth.AccessTTL).Unix(),
+		},
+	}
+
+	accessToken, err := auth.GenerateToken(accessClaims, s.cfg.Auth.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshID, err := helper.RandomHex(16)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate token id")
+	}
+
+	refreshExpiresAt := now.Add(s.cfg.Auth.RefreshTTL)
+	refreshClaims := UserClaims{
+		ID:            userID,
+		Type:          TokenTypeRefresh,
+		Role:          user.Role,
+		IssuedAtMicro: now.UnixMicro(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        refreshID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: refreshExpiresAt.Unix(),
+		},
+	}
+
+	refreshToken, err := auth.GenerateToken(refreshClaims, s.cfg.Auth.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.r.CreateRefreshToken(RefreshToken{
+		UserID:    userID,
+		TokenID:   refreshID,
+		FamilyID:  familyID,
+		ExpiresAt: refreshExpiresAt,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to store refresh token")
+	}
+
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ResponseID:   common.ResponseID{ID: userID},
+	}, nil
 }
@@ -0,0 +1,417 @@
+package user
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/config"
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/auth"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// fakeRepository keeps users, refresh tokens and login attempts in memory.
+// Methods the tests do not reach are left to the embedded nil Repository and
+// panic if called.
+type fakeRepository struct {
+	Repository
+
+	users    map[uint]*User
+	refresh  map[string]*RefreshToken
+	revoked  []string
+	attempts map[string]*LoginAttempt
+}
+
+func newFakeRepository(users ...User) *fakeRepository {
+	r := &fakeRepository{
+		users:    make(map[uint]*User),
+		refresh:  make(map[string]*RefreshToken),
+		attempts: make(map[string]*LoginAttempt),
+	}
+	for i := range users {
+		r.users[users[i].ID] = &users[i]
+	}
+	return r
+}
+
+func (r *fakeRepository) GetByID(req common.RequestID) (*User, error) {
+	user, ok := r.users[req.ID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeRepository) GetByEmail(email string) (*User, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeRepository) CreateRefreshToken(token RefreshToken) error {
+	r.refresh[token.TokenID] = &token
+	return nil
+}
+
+func (r *fakeRepository) GetRefreshToken(tokenID string) (*RefreshToken, error) {
+	token, ok := r.refresh[tokenID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (r *fakeRepository) UseRefreshToken(tokenID string) (bool, error) {
+	token, ok := r.refresh[tokenID]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return true, nil
+}
+
+func (r *fakeRepository) RevokeRefreshTokenFamily(familyID string) error {
+	now := time.Now()
+	for _, token := range r.refresh {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	r.revoked = append(r.revoked, familyID)
+	return nil
+}
+
+func (r *fakeRepository) GetLoginAttempt(scope, key string) (*LoginAttempt, error) {
+	attempt, ok := r.attempts[scope+":"+key]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return attempt, nil
+}
+
+func (r *fakeRepository) RecordLoginFailure(scope, key string, window time.Duration) (int, error) {
+	now := time.Now()
+	attempt, ok := r.attempts[scope+":"+key]
+	if !ok {
+		attempt = &LoginAttempt{Scope: scope, Key: key}
+		r.attempts[scope+":"+key] = attempt
+	}
+	if attempt.LastFailedAt.Before(now.Add(-window)) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailedAt = now
+	return attempt.Failures, nil
+}
+
+func (r *fakeRepository) LockLogin(scope, key string, until time.Time) error {
+	if attempt, ok := r.attempts[scope+":"+key]; ok {
+		attempt.Failures = 0
+		attempt.LockedUntil = &until
+	}
+	return nil
+}
+
+func (r *fakeRepository) ResetLoginAttempts(scope, key string) error {
+	delete(r.attempts, scope+":"+key)
+	return nil
+}
+
+func (r *fakeRepository) failures(scope, key string) int {
+	if attempt, ok := r.attempts[scope+":"+key]; ok {
+		return attempt.Failures
+	}
+	return 0
+}
+
+func testConfig() *config.Config {
+	return &config.Config{Auth: config.Auth{
+		AccessTTL:        time.Minute,
+		RefreshTTL:       time.Hour,
+		SecretKey:        "secret",
+		MaxLoginAttempts: 3,
+		MaxIPAttempts:    5,
+		LockoutDuration:  15 * time.Minute,
+	}}
+}
+
+func newTestService(r Repository) *service {
+	return &service{r: r, cfg: testConfig()}
+}
+
+func refreshID(t *testing.T, token string) string {
+	t.Helper()
+
+	var claims UserClaims
+	if err := auth.ParseToken(token, "secret", &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims.Id
+}
+
+func TestRefreshTokensRotates(t *testing.T) {
+	repo := newFakeRepository(User{Model: gorm.Model{ID: 1}, Role: RoleEditor})
+	s := newTestService(repo)
+
+	first, err := s.GenerateTokens(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.RefreshTokens(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldID, newID := refreshID(t, first.RefreshToken), refreshID(t, second.RefreshToken)
+	if oldID == newID {
+		t.Fatal("the refresh token was not rotated")
+	}
+	old, current := repo.refresh[oldID], repo.refresh[newID]
+	if old.UsedAt == nil {
+		t.Error("the presented token is not marked used")
+	}
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		t.Errorf("the new token is already spent: %+v", current)
+	}
+	if old.FamilyID != current.FamilyID {
+		t.Errorf("the new token left the family: %q, want %q", current.FamilyID, old.FamilyID)
+	}
+
+	var claims UserClaims
+	if err := auth.ParseToken(second.AccessToken, "secret", &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Type != TokenTypeAccess || claims.ID != 1 || claims.Role != RoleEditor {
+		t.Errorf("access claims = %+v", claims)
+	}
+
+	if _, err := s.RefreshTokens(second.RefreshToken); err != nil {
+		t.Fatalf("the rotated token does not refresh: %v", err)
+	}
+}
+
+func TestRefreshTokensReuseRevokesFamily(t *testing.T) {
+	repo := newFakeRepository(User{Model: gorm.Model{ID: 1}})
+	s := newTestService(repo)
+
+	first, err := s.GenerateTokens(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.RefreshTokens(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.GenerateTokens(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RefreshTokens(first.RefreshToken); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("err = %v, want %v", err, ErrTokenReused)
+	}
+
+	family := repo.refresh[refreshID(t, first.RefreshToken)].FamilyID
+	if len(repo.revoked) != 1 || repo.revoked[0] != family {
+		t.Fatalf("revoked families = %q, want [%q]", repo.revoked, family)
+	}
+
+	// The thief's successor dies with the family, another session does not.
+	if _, err := s.RefreshTokens(second.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("successor: err = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := s.RefreshTokens(other.RefreshToken); err != nil {
+		t.Errorf("other session: %v", err)
+	}
+}
+
+func TestRefreshTokensRejects(t *testing.T) {
+	repo := newFakeRepository(User{Model: gorm.Model{ID: 1}}, User{Model: gorm.Model{ID: 2}})
+	s := newTestService(repo)
+
+	pair, err := s.GenerateTokens(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := s.GenerateTokens(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.refresh[refreshID(t, expired.RefreshToken)].ExpiresAt = time.Now().Add(-time.Second)
+
+	unknown, err := s.GenerateTokens(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(repo.refresh, refreshID(t, unknown.RefreshToken))
+
+	foreign, err := s.GenerateTokens(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.refresh[refreshID(t, foreign.RefreshToken)].UserID = 2
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "access token", token: pair.AccessToken},
+		{name: "not a token", token: "garbage"},
+		{name: "expired", token: expired.RefreshToken},
+		{name: "not stored", token: unknown.RefreshToken},
+		{name: "stored for another user", token: foreign.RefreshToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.RefreshTokens(tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+
+	if len(repo.revoked) != 0 {
+		t.Errorf("rejected tokens revoked families %q", repo.revoked)
+	}
+}
+
+func loginUser(t *testing.T, id uint, email string) User {
+	t.Helper()
+
+	hash, err := helper.PasswordHash("right")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return User{Model: gorm.Model{ID: id}, Email: email, Password: hash}
+}
+
+func login(s *service, email, password, ip string) error {
+	_, err := s.Authenticate(LoginInput{Email: email, Password: password}, ip)
+	return err
+}
+
+func wantLocked(t *testing.T, err error, max time.Duration) {
+	t.Helper()
+
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("err = %v, want a LockedError", err)
+	}
+	if locked.RetryAfter <= 0 || locked.RetryAfter > max {
+		t.Fatalf("RetryAfter = %s, want up to %s", locked.RetryAfter, max)
+	}
+}
+
+func TestAuthenticateLocksEmail(t *testing.T) {
+	repo := newFakeRepository(loginUser(t, 1, "a@example.com"))
+	s := newTestService(repo)
+	lockout := s.cfg.Auth.LockoutDuration
+
+	for i := 1; i < s.cfg.Auth.MaxLoginAttempts; i++ {
+		if err := login(s, "a@example.com", "wrong", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failure %d: err = %v, want %v", i, err, ErrInvalidCredentials)
+		}
+	}
+
+	err := login(s, "A@example.com", "wrong", "10.0.0.2")
+	wantLocked(t, err, lockout)
+	if got := err.(*LockedError).RetryAfter; got != lockout {
+		t.Errorf("RetryAfter = %s, want %s", got, lockout)
+	}
+
+	// The lock holds for the right password and any client.
+	wantLocked(t, login(s, "a@example.com", "right", "10.0.0.3"), lockout)
+
+	until := time.Now().Add(-time.Second)
+	repo.attempts[AttemptScopeEmail+":a@example.com"].LockedUntil = &until
+	if err := login(s, "a@example.com", "right", "10.0.0.3"); err != nil {
+		t.Fatalf("after the lock ran out: %v", err)
+	}
+}
+
+func TestAuthenticateLocksIP(t *testing.T) {
+	repo := newFakeRepository(loginUser(t, 1, "a@example.com"))
+	s := newTestService(repo)
+	limit := s.cfg.Auth.MaxIPAttempts
+
+	// Guessing across accounts stays under each email limit.
+	for i := 1; i < limit; i++ {
+		email := strings.Repeat("x", i) + "@example.com"
+		if err := login(s, email, "wrong", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failure %d: err = %v, want %v", i, err, ErrInvalidCredentials)
+		}
+	}
+	wantLocked(t, login(s, "y@example.com", "wrong", "10.0.0.1"), s.cfg.Auth.LockoutDuration)
+
+	wantLocked(t, login(s, "a@example.com", "right", "10.0.0.1"), s.cfg.Auth.LockoutDuration)
+	if err := login(s, "a@example.com", "right", "10.0.0.2"); err != nil {
+		t.Fatalf("from another client: %v", err)
+	}
+}
+
+func TestAuthenticateSuccessResetsOnlyEmail(t *testing.T) {
+	repo := newFakeRepository(loginUser(t, 1, "a@example.com"))
+	s := newTestService(repo)
+
+	for i := 0; i < 2; i++ {
+		if err := login(s, "a@example.com", "wrong", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatal(err)
+		}
+	}
+	if err := login(s, "a@example.com", "right", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := repo.failures(AttemptScopeEmail, "a@example.com"); got != 0 {
+		t.Errorf("email failures = %d, want 0", got)
+	}
+	if got := repo.failures(AttemptScopeIP, "10.0.0.1"); got != 2 {
+		t.Errorf("ip failures = %d, want 2", got)
+	}
+}
+
+func TestAuthenticateLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxEmail int
+		maxIP    int
+		ip       string
+		tries    int
+		locked   bool
+	}{
+		{name: "below the email limit", maxEmail: 3, maxIP: 5, ip: "10.0.0.1", tries: 2},
+		{name: "at the email limit", maxEmail: 3, maxIP: 5, ip: "10.0.0.1", tries: 3, locked: true},
+		{name: "at the ip limit", maxEmail: 5, maxIP: 2, ip: "10.0.0.1", tries: 2, locked: true},
+		{name: "unknown client ip is not counted", maxEmail: 5, maxIP: 2, tries: 4},
+		{name: "zero disables the limits", maxEmail: 0, maxIP: 0, ip: "10.0.0.1", tries: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(newFakeRepository())
+			s.cfg.Auth.MaxLoginAttempts, s.cfg.Auth.MaxIPAttempts = tt.maxEmail, tt.maxIP
+
+			var err error
+			for i := 0; i < tt.tries; i++ {
+				err = login(s, "nobody@example.com", "wrong", tt.ip)
+			}
+
+			var locked *LockedError
+			if got := errors.As(err, &locked); got != tt.locked {
+				t.Fatalf("err = %v, locked = %v, want %v", err, got, tt.locked)
+			}
+			if !tt.locked && !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidCredentials)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package watchlist
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+)
+
+// Item is a movie on the watchlist of a user. Higher priorities are listed
+// first.
+type Item struct {
+	UserID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	MovieID   uint   `gorm:"primaryKey;autoIncrement:false;index"`
+	Note      string `gorm:"type:varchar(500);not null;default:''"`
+	Priority  int    `gorm:"type:smallint;not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (Item) TableName() string {
+	return "watchlist_items"
+}
+
+// Event records that a user watched a movie. A movie can be watched any
+// number of times.
+type Event struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index:idx_watch_events_user_watched,priority:1"`
+	MovieID   uint      `gorm:"not null;index"`
+	WatchedAt time.Time `gorm:"not null;index:idx_watch_events_user_watched,priority:2"`
+	CreatedAt time.Time
+}
+
+func (Event) TableName() string {
+	return "watch_events"
+}
+
+type ItemInput struct {
+	UserID   uint   `json:"-"`
+	MovieID  uint   `json:"-"`
+	Note     string `json:"note,omitempty" validate:"max=500"`
+	Priority int    `json:"priority,omitempty" validate:"min=0,max=5"`
+}
+
+// EventInput logs a watch, at WatchedAt or now when it is not set.
+type EventInput struct {
+	UserID    uint       `json:"-"`
+	MovieID   uint       `json:"movie_id" validate:"required"`
+	WatchedAt *time.Time `json:"watched_at,omitempty"`
+}
+
+type ItemResponse struct {
+	Movie     common.ResponseRef `json:"movie"`
+	Year      int                `json:"year"`
+	Note      string             `json:"note,omitempty"`
+	Priority  int                `json:"priority"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+type EventResponse struct {
+	ID        uint               `json:"id"`
+	Movie     common.ResponseRef `json:"movie"`
+	Year      int                `json:"year"`
+	WatchedAt time.Time          `json:"watched_at"`
+}
+
+type ItemListResponse struct {
+	Items []ItemResponse `json:"items"`
+	Total uint64         `json:"total"`
+}
+
+type EventListResponse struct {
+	Events []EventResponse `json:"events"`
+	Total  uint64          `json:"total"`
+}
+
+type ListFilter struct {
+	UserID  uint
+	MovieID uint
+	Page    int64
+	Limit   int64
+}
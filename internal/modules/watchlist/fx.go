@@ -0,0 +1,12 @@
+package watchlist
+
+import "go.uber.org/fx"
+
+var Module = fx.Module(
+	"watchlist_module",
+	fx.Provide(
+		NewRepository,
+		NewService,
+		NewHandler,
+	),
+)
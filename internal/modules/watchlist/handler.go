@@ -0,0 +1,399 @@
+package watchlist
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type Handler interface {
+	Add(c *gin.Context)
+	Remove(c *gin.Context)
+	List(c *gin.Context)
+	LogWatch(c *gin.Context)
+	DeleteWatch(c *gin.Context)
+	History(c *gin.Context)
+}
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(service Service) Handler {
+	return &handler{service: service}
+}
+
+// @Summary Add a movie to my watchlist
+// @Description Add a movie to the watchlist of the current user, or update its note and priority
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param movie_id path string true "Movie ID"
+// @Param item body ItemInput false "Note and priority from 0 to 5"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/watchlist/{movie_id} [put]
+func (h *handler) Add(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("movie_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	var req ItemInput
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.UserID = c.GetUint("user_id")
+	req.MovieID = uint(movieID)
+	item, err := h.service.Add(req)
+	if err != nil {
+		if errors.Is(err, ErrMovieNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Movie added to watchlist",
+			ID:      item.ID,
+		},
+	)
+}
+
+// @Summary Remove a movie from my watchlist
+// @Description Remove a movie from the watchlist of the current user
+// @Tags me
+// @Produce json
+// @Param movie_id path string true "Movie ID"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/watchlist/{movie_id} [delete]
+func (h *handler) Remove(c *gin.Context) {
+	movieID, err := strconv.ParseUint(c.Param("movie_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid movie id",
+			},
+		)
+		return
+	}
+
+	item, err := h.service.Remove(c.GetUint("user_id"), uint(movieID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie is not on the watchlist",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "Movie removed from watchlist",
+			ID:      item.ID,
+		},
+	)
+}
+
+// @Summary Get my watchlist
+// @Description Get the watchlist of the current user, highest priority first
+// @Tags me
+// @Produce json
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(20)
+// @Success 200 {object} common.ResponseWithList
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/watchlist [get]
+func (h *handler) List(c *gin.Context) {
+	filter := pageFilter(c)
+	filter.UserID = c.GetUint("user_id")
+
+	items, err := h.service.List(filter)
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseWithList{
+			Status:  http.StatusOK,
+			Message: "Watchlist fetched successfully",
+			Total:   items.Total,
+			Data:    items.Items,
+		},
+	)
+}
+
+// @Summary Log a watched movie
+// @Description Record that the current user watched a movie, now or at watched_at
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param event body EventInput true "Watch"
+// @Success 201 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/history [post]
+func (h *handler) LogWatch(c *gin.Context) {
+	var req EventInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid body",
+			},
+		)
+		return
+	}
+
+	if err := common.Validate.Struct(req); err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	req.UserID = c.GetUint("user_id")
+	event, err := h.service.LogWatch(req)
+	if err != nil {
+		if errors.Is(err, ErrMovieNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "Movie not found",
+				},
+			)
+			return
+		}
+
+		if errors.Is(err, ErrFutureWatch) {
+			c.JSON(
+				http.StatusBadRequest,
+				common.ResponseError{
+					Status:  http.StatusBadRequest,
+					Message: err.Error(),
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusCreated,
+		common.ResponseID{
+			Status:  http.StatusCreated,
+			Message: "Watch logged successfully",
+			ID:      event.ID,
+		},
+	)
+}
+
+// @Summary Delete a watch from my history
+// @Description Delete an entry of the watch history of the current user
+// @Tags me
+// @Produce json
+// @Param id path string true "History entry ID"
+// @Success 200 {object} common.ResponseID
+// @Failure 400 {object} common.ResponseError
+// @Failure 404 {object} common.ResponseError
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/history/{id} [delete]
+func (h *handler) DeleteWatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			common.ResponseError{
+				Status:  http.StatusBadRequest,
+				Message: "Invalid history id",
+			},
+		)
+		return
+	}
+
+	event, err := h.service.DeleteWatch(c.GetUint("user_id"), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(
+				http.StatusNotFound,
+				common.ResponseError{
+					Status:  http.StatusNotFound,
+					Message: "History entry not found",
+				},
+			)
+			return
+		}
+
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseID{
+			Status:  http.StatusOK,
+			Message: "History entry deleted successfully",
+			ID:      event.ID,
+		},
+	)
+}
+
+// @Summary Get my watch history
+// @Description Get the watch history of the current user, most recent first
+// @Tags me
+// @Produce json
+// @Param movie_id query int false "Only watches of this movie"
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Limit" default(20)
+// @Success 200 {object} common.ResponseWithList
+// @Failure 500 {object} common.ResponseError
+// @Security ApiKeyAuth
+// @Router /api/v1/me/history [get]
+func (h *handler) History(c *gin.Context) {
+	filter := pageFilter(c)
+	filter.UserID = c.GetUint("user_id")
+	if movieID, err := strconv.ParseUint(c.Query("movie_id"), 10, 64); err == nil {
+		filter.MovieID = uint(movieID)
+	}
+
+	events, err := h.service.History(filter)
+	if err != nil {
+		c.JSON(
+			http.StatusInternalServerError,
+			common.ResponseError{
+				Status:  http.StatusInternalServerError,
+				Message: err.Error(),
+			},
+		)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		common.ResponseWithList{
+			Status:  http.StatusOK,
+			Message: "History fetched successfully",
+			Total:   events.Total,
+			Data:    events.Events,
+		},
+	)
+}
+
+func pageFilter(c *gin.Context) ListFilter {
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+
+	return ListFilter{Page: page, Limit: limit}
+}
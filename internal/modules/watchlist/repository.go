@@ -0,0 +1,179 @@
+package watchlist
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/asliddinberdiev/i_tv_task/internal/storage/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	Upsert(req Item) (*common.ResponseID, error)
+	Delete(userID, movieID uint) (*common.ResponseID, error)
+	List(filter ListFilter) (*ItemListResponse, error)
+	Contains(userID uint, movieIDs []uint) ([]uint, error)
+	CreateEvent(req Event) (*common.ResponseID, error)
+	DeleteEvent(userID, id uint) (*common.ResponseID, error)
+	ListEvents(filter ListFilter) (*EventListResponse, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(psql postgres.PostgresDB) Repository {
+	return &repository{db: psql.DB()}
+}
+
+// Upsert adds the movie to the watchlist, or updates its note and priority
+// when it is already there.
+func (r *repository) Upsert(req Item) (*common.ResponseID, error) {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "movie_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"note", "priority", "updated_at"}),
+	}).Create(&req).Error; err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.MovieID}, nil
+}
+
+func (r *repository) Delete(userID, movieID uint) (*common.ResponseID, error) {
+	res := r.db.Where("user_id = ? AND movie_id = ?", userID, movieID).Delete(&Item{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &common.ResponseID{ID: movieID}, nil
+}
+
+func (r *repository) List(filter ListFilter) (*ItemListResponse, error) {
+	response := ItemListResponse{
+		Items: make([]ItemResponse, 0),
+	}
+
+	from := `
+		FROM watchlist_items w
+		JOIN movies m ON m.id = w.movie_id AND m.deleted_at IS NULL
+		WHERE w.user_id = ?`
+
+	var total int64
+	if err := r.db.Raw(`SELECT COUNT(*) `+from, filter.UserID).Scan(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		MovieID   uint
+		Title     string
+		Year      int
+		Note      string
+		Priority  int
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+	if err := r.db.Raw(`
+		SELECT w.movie_id, m.title, m.year, w.note, w.priority, w.created_at, w.updated_at `+from+`
+		ORDER BY w.priority DESC, w.created_at DESC, w.movie_id DESC
+		LIMIT ? OFFSET ?
+	`, filter.UserID, filter.Limit, (filter.Page-1)*filter.Limit).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		response.Items = append(response.Items, ItemResponse{
+			Movie:     common.ResponseRef{ID: row.MovieID, Name: row.Title},
+			Year:      row.Year,
+			Note:      row.Note,
+			Priority:  row.Priority,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		})
+	}
+
+	response.Total = uint64(total)
+	return &response, nil
+}
+
+// Contains returns which of the movies are on the watchlist of the user.
+func (r *repository) Contains(userID uint, movieIDs []uint) ([]uint, error) {
+	ids := make([]uint, 0)
+	if len(movieIDs) == 0 {
+		return ids, nil
+	}
+
+	if err := r.db.Model(&Item{}).
+		Where("user_id = ? AND movie_id IN ?", userID, movieIDs).
+		Pluck("movie_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *repository) CreateEvent(req Event) (*common.ResponseID, error) {
+	if err := r.db.Create(&req).Error; err != nil {
+		return nil, err
+	}
+	return &common.ResponseID{ID: req.ID}, nil
+}
+
+func (r *repository) DeleteEvent(userID, id uint) (*common.ResponseID, error) {
+	res := r.db.Where("user_id = ?", userID).Delete(&Event{}, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &common.ResponseID{ID: id}, nil
+}
+
+func (r *repository) ListEvents(filter ListFilter) (*EventListResponse, error) {
+	response := EventListResponse{
+		Events: make([]EventResponse, 0),
+	}
+
+	from := `
+		FROM watch_events e
+		JOIN movies m ON m.id = e.movie_id AND m.deleted_at IS NULL
+		WHERE e.user_id = ?`
+	args := []interface{}{filter.UserID}
+	if filter.MovieID != 0 {
+		from += ` AND e.movie_id = ?`
+		args = append(args, filter.MovieID)
+	}
+
+	var total int64
+	if err := r.db.Raw(`SELECT COUNT(*) `+from, args...).Scan(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID        uint
+		MovieID   uint
+		Title     string
+		Year      int
+		WatchedAt time.Time
+	}
+	if err := r.db.Raw(`
+		SELECT e.id, e.movie_id, m.title, m.year, e.watched_at `+from+`
+		ORDER BY e.watched_at DESC, e.id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, filter.Limit, (filter.Page-1)*filter.Limit)...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		response.Events = append(response.Events, EventResponse{
+			ID:        row.ID,
+			Movie:     common.ResponseRef{ID: row.MovieID, Name: row.Title},
+			Year:      row.Year,
+			WatchedAt: row.WatchedAt,
+		})
+	}
+
+	response.Total = uint64(total)
+	return &response, nil
+}
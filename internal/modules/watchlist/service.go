@@ -0,0 +1,105 @@
+package watchlist
+
+import (
+	"time"
+
+	"github.com/asliddinberdiev/i_tv_task/internal/modules/common"
+	"github.com/pkg/errors"
+)
+
+type Service interface {
+	Add(req ItemInput) (*common.ResponseID, error)
+	Remove(userID, movieID uint) (*common.ResponseID, error)
+	List(filter ListFilter) (*ItemListResponse, error)
+	Contains(userID uint, movieIDs []uint) (map[uint]bool, error)
+	LogWatch(req EventInput) (*common.ResponseID, error)
+	DeleteWatch(userID, id uint) (*common.ResponseID, error)
+	History(filter ListFilter) (*EventListResponse, error)
+}
+
+var (
+	ErrMovieNotFound = errors.New("movie not found")
+	ErrFutureWatch   = errors.New("watched_at must not be in the future")
+)
+
+type service struct {
+	repo   Repository
+	movies common.MovieChecker
+}
+
+func NewService(repo Repository, movies common.MovieChecker) Service {
+	return &service{repo: repo, movies: movies}
+}
+
+func (s *service) Add(req ItemInput) (*common.ResponseID, error) {
+	if err := s.movieExists(req.MovieID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Upsert(Item{
+		UserID:   req.UserID,
+		MovieID:  req.MovieID,
+		Note:     req.Note,
+		Priority: req.Priority,
+	})
+}
+
+func (s *service) Remove(userID, movieID uint) (*common.ResponseID, error) {
+	return s.repo.Delete(userID, movieID)
+}
+
+func (s *service) List(filter ListFilter) (*ItemListResponse, error) {
+	return s.repo.List(filter)
+}
+
+func (s *service) Contains(userID uint, movieIDs []uint) (map[uint]bool, error) {
+	ids, err := s.repo.Contains(userID, movieIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	contains := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		contains[id] = true
+	}
+	return contains, nil
+}
+
+func (s *service) LogWatch(req EventInput) (*common.ResponseID, error) {
+	if err := s.movieExists(req.MovieID); err != nil {
+		return nil, err
+	}
+
+	watchedAt := time.Now()
+	if req.WatchedAt != nil {
+		if req.WatchedAt.After(watchedAt) {
+			return nil, ErrFutureWatch
+		}
+		watchedAt = *req.WatchedAt
+	}
+
+	return s.repo.CreateEvent(Event{
+		UserID:    req.UserID,
+		MovieID:   req.MovieID,
+		WatchedAt: watchedAt,
+	})
+}
+
+func (s *service) DeleteWatch(userID, id uint) (*common.ResponseID, error) {
+	return s.repo.DeleteEvent(userID, id)
+}
+
+func (s *service) History(filter ListFilter) (*EventListResponse, error) {
+	return s.repo.ListEvents(filter)
+}
+
+func (s *service) movieExists(id uint) error {
+	exists, err := s.movies.MovieExists(id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrMovieNotFound
+	}
+	return nil
+}
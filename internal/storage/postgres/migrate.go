@@ -0,0 +1,16 @@
+package postgres
+
+import (
+	"github.com/asliddinberdiev/i_tv_task/migrations"
+	"github.com/asliddinberdiev/i_tv_task/pkgs/migrate"
+	"github.com/pkg/errors"
+)
+
+// NewMigrator returns a migrator for the migrations embedded in the binary.
+func NewMigrator(psql PostgresDB) (*migrate.Migrator, error) {
+	sqlDB, err := psql.DB().DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get sql.DB from gorm")
+	}
+	return migrate.New(sqlDB, migrations.Postgres, "postgres")
+}
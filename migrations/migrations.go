@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migrations into the binary.
+package migrations
+
+import "embed"
+
+// Postgres holds the migrations of the postgres database, as
+// NNNNNN_name.up.sql and NNNNNN_name.down.sql pairs.
+//
+//go:embed postgres/*.sql
+var Postgres embed.FS
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS movies (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    title VARCHAR(255) NOT NULL,
+    year INT NOT NULL,
+    genre VARCHAR(255) NOT NULL,
+    rating FLOAT NOT NULL,
+    director VARCHAR(255) NOT NULL,
+    CONSTRAINT uni_movies_title UNIQUE (title)
+);
+
+CREATE INDEX IF NOT EXISTS idx_movies_deleted_at ON movies (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_movies_title ON movies (title);
+CREATE INDEX IF NOT EXISTS idx_movies_year ON movies (year);
+CREATE INDEX IF NOT EXISTS idx_movies_genre ON movies (genre);
+CREATE INDEX IF NOT EXISTS idx_movies_rating ON movies (rating);
+CREATE INDEX IF NOT EXISTS idx_movies_director ON movies (director);
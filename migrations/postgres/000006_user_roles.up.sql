@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'viewer';
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
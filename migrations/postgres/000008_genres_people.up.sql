@@ -0,0 +1,96 @@
+CREATE TABLE IF NOT EXISTS genres (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_genres_deleted_at ON genres (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_genres_name ON genres (name) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS people (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_people_deleted_at ON people (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_people_name ON people (lower(name));
+
+CREATE TABLE IF NOT EXISTS movie_genres (
+    movie_id BIGINT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    genre_id BIGINT NOT NULL REFERENCES genres (id),
+    PRIMARY KEY (movie_id, genre_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_movie_genres_genre_id ON movie_genres (genre_id);
+
+CREATE TABLE IF NOT EXISTS movie_directors (
+    movie_id BIGINT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    person_id BIGINT NOT NULL REFERENCES people (id),
+    PRIMARY KEY (movie_id, person_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_movie_directors_person_id ON movie_directors (person_id);
+
+-- Turn the free-form strings into rows. Genres are normalized the way the
+-- application does it (lowercase, single spaces) and split on commas.
+CREATE TEMPORARY TABLE movie_genre_names AS
+SELECT DISTINCT m.id AS movie_id,
+    lower(regexp_replace(trim(part), '\s+', ' ', 'g')) AS name
+FROM movies m, regexp_split_to_table(m.genre, ',') AS part
+WHERE trim(part) <> '';
+
+INSERT INTO genres (created_at, updated_at, name)
+SELECT DISTINCT now(), now(), name FROM movie_genre_names
+ON CONFLICT (name) WHERE deleted_at IS NULL DO NOTHING;
+
+INSERT INTO movie_genres (movie_id, genre_id)
+SELECT n.movie_id, g.id
+FROM movie_genre_names n
+JOIN genres g ON g.name = n.name AND g.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+CREATE TEMPORARY TABLE movie_director_names AS
+SELECT DISTINCT id AS movie_id, regexp_replace(trim(director), '\s+', ' ', 'g') AS name
+FROM movies
+WHERE trim(director) <> '';
+
+INSERT INTO people (created_at, updated_at, name)
+SELECT now(), now(), n.name
+FROM (SELECT DISTINCT ON (lower(name)) name FROM movie_director_names ORDER BY lower(name), name) AS n
+WHERE NOT EXISTS (
+    SELECT 1 FROM people p WHERE lower(p.name) = lower(n.name) AND p.deleted_at IS NULL
+);
+
+INSERT INTO movie_directors (movie_id, person_id)
+SELECT n.movie_id, (
+    SELECT p.id FROM people p
+    WHERE lower(p.name) = lower(n.name) AND p.deleted_at IS NULL
+    ORDER BY p.id
+    LIMIT 1
+)
+FROM movie_director_names n
+ON CONFLICT DO NOTHING;
+
+DROP TABLE movie_genre_names;
+DROP TABLE movie_director_names;
+
+-- movies.genre and movies.director stay as denormalized, comma separated
+-- names of the links; search and sorting read them.
+UPDATE movies AS m
+SET genre = COALESCE((
+        SELECT string_agg(g.name, ', ' ORDER BY g.name)
+        FROM movie_genres mg
+        JOIN genres g ON g.id = mg.genre_id AND g.deleted_at IS NULL
+        WHERE mg.movie_id = m.id
+    ), ''),
+    director = COALESCE((
+        SELECT string_agg(p.name, ', ' ORDER BY p.name)
+        FROM movie_directors md
+        JOIN people p ON p.id = md.person_id AND p.deleted_at IS NULL
+        WHERE md.movie_id = m.id
+    ), '');
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS credits (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    movie_id BIGINT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    person_id BIGINT NOT NULL REFERENCES people (id) ON DELETE RESTRICT,
+    role VARCHAR(32) NOT NULL,
+    character_name VARCHAR(255) NOT NULL DEFAULT '',
+    billing_order BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_credits_deleted_at ON credits (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_credits_movie_billing ON credits (movie_id, billing_order);
+CREATE INDEX IF NOT EXISTS idx_credits_person_id ON credits (person_id);
+CREATE INDEX IF NOT EXISTS idx_credits_role ON credits (role);
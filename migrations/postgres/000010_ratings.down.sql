@@ -0,0 +1,10 @@
+UPDATE movies SET rating = COALESCE(legacy_rating, 0);
+
+ALTER TABLE movies DROP COLUMN IF EXISTS legacy_rating;
+
+DROP INDEX IF EXISTS idx_movies_rating_count;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS rating_count;
+
+DROP TABLE IF EXISTS movie_rating_stats;
+DROP TABLE IF EXISTS ratings;
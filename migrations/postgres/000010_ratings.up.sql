@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS ratings (
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    movie_id BIGINT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 10),
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    PRIMARY KEY (user_id, movie_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ratings_movie_id ON ratings (movie_id);
+
+CREATE TABLE IF NOT EXISTS movie_rating_stats (
+    movie_id BIGINT PRIMARY KEY REFERENCES movies (id) ON DELETE CASCADE,
+    count BIGINT NOT NULL DEFAULT 0,
+    sum BIGINT NOT NULL DEFAULT 0,
+    score_1 BIGINT NOT NULL DEFAULT 0,
+    score_2 BIGINT NOT NULL DEFAULT 0,
+    score_3 BIGINT NOT NULL DEFAULT 0,
+    score_4 BIGINT NOT NULL DEFAULT 0,
+    score_5 BIGINT NOT NULL DEFAULT 0,
+    score_6 BIGINT NOT NULL DEFAULT 0,
+    score_7 BIGINT NOT NULL DEFAULT 0,
+    score_8 BIGINT NOT NULL DEFAULT 0,
+    score_9 BIGINT NOT NULL DEFAULT 0,
+    score_10 BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ
+);
+
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS rating_count BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_movies_rating_count ON movies (rating_count);
+
+-- movies.rating becomes the mean of the user ratings. The editor-set values
+-- are kept aside so that the down migration can restore them.
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS legacy_rating FLOAT;
+
+UPDATE movies SET legacy_rating = rating, rating = 0 WHERE legacy_rating IS NULL;
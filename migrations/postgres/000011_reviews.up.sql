@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    movie_id BIGINT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    spoiler BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    moderated_by BIGINT REFERENCES users (id) ON DELETE SET NULL,
+    moderated_at TIMESTAMPTZ,
+    moderation_note VARCHAR(500) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_deleted_at ON reviews (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id ON reviews (user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_movie_user ON reviews (movie_id, user_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS review_reports (
+    id BIGSERIAL PRIMARY KEY,
+    review_id BIGINT NOT NULL REFERENCES reviews (id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reason VARCHAR(500) NOT NULL,
+    created_at TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_review_reports_review_user ON review_reports (review_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_review_reports_resolved_at ON review_reports (resolved_at);
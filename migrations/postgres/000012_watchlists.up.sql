@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS watchlist_items (
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    movie_id BIGINT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    note VARCHAR(500) NOT NULL DEFAULT '',
+    priority SMALLINT NOT NULL DEFAULT 0 CHECK (priority BETWEEN 0 AND 5),
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    PRIMARY KEY (user_id, movie_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watchlist_items_movie_id ON watchlist_items (movie_id);
+
+CREATE TABLE IF NOT EXISTS watch_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    movie_id BIGINT NOT NULL REFERENCES movies (id) ON DELETE CASCADE,
+    watched_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_watch_events_user_watched ON watch_events (user_id, watched_at);
+CREATE INDEX IF NOT EXISTS idx_watch_events_movie_id ON watch_events (movie_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_movies_title_live;
+
+-- Fails while a deleted movie shares its title with another movie; purge
+-- those first.
+ALTER TABLE movies ADD CONSTRAINT movies_title_key UNIQUE (title);
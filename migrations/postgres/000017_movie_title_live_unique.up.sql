@@ -0,0 +1,4 @@
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS movies_title_key;
+ALTER TABLE movies DROP CONSTRAINT IF EXISTS uni_movies_title;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_movies_title_live ON movies (title) WHERE deleted_at IS NULL;
@@ -0,0 +1,42 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalid = errors.New("invalid cursor")
+
+// Encode serializes v into an opaque, URL safe token signed with key.
+func Encode(v interface{}, key string) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal cursor")
+	}
+
+	data := base64.RawURLEncoding.EncodeToString(payload)
+	return data + "." + helper.HMACHash(key, data), nil
+}
+
+// Decode verifies the signature of token and unmarshals it into v.
+func Decode(token, key string, v interface{}) error {
+	data, signature, ok := strings.Cut(token, ".")
+	if !ok || !helper.HMACCompare(key, signature, data) {
+		return ErrInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return ErrInvalid
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		return ErrInvalid
+	}
+
+	return nil
+}
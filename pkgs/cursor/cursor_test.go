@@ -0,0 +1,70 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/asliddinberdiev/i_tv_task/pkgs/helper"
+	"github.com/pkg/errors"
+)
+
+type page struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := page{Sort: "title:asc,id:asc", Values: []string{"Alien", "7"}}
+
+	token, err := Encode(in, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsAny(token, "+/=") {
+		t.Fatalf("token %q is not URL safe", token)
+	}
+
+	var out page
+	if err := Decode(token, "key", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("decoded %+v, want %+v", out, in)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	token, err := Encode(page{Sort: "title:asc,id:asc", Values: []string{"Alien", "7"}}, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, signature, _ := strings.Cut(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"title:desc,id:desc","v":["Alien","7"]}`))
+	garbage := base64.RawURLEncoding.EncodeToString([]byte(`not json`))
+
+	tests := []struct {
+		name  string
+		token string
+		key   string
+	}{
+		{name: "other key", token: token, key: "other"},
+		{name: "payload swapped under the signature", token: forged + "." + signature, key: "key"},
+		{name: "signature cut short", token: data + "." + signature[:len(signature)-2], key: "key"},
+		{name: "no signature", token: data, key: "key"},
+		{name: "empty", token: "", key: "key"},
+		{name: "signed payload that is not JSON", token: garbage + "." + helper.HMACHash("key", garbage), key: "key"},
+		{name: "signed payload that is not base64", token: "%%%." + helper.HMACHash("key", "%%%"), key: "key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out page
+			if err := Decode(tt.token, tt.key, &out); !errors.Is(err, ErrInvalid) {
+				t.Fatalf("err = %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+}
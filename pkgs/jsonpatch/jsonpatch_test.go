@@ -0,0 +1,307 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// The cases follow the examples of RFC 6902 appendix A and RFC 7396
+// appendix A, with a few more for the edges they leave out.
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{
+			name:  "A.1 adding an object member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:  "A.2 adding an array element",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:  "A.3 removing an object member",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		{
+			name:  "A.4 removing an array element",
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:  "A.5 replacing a value",
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:  "A.6 moving a value",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:  "A.7 moving an array element",
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name: "A.8 testing a value, success",
+			doc:  `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[
+				{"op": "test", "path": "/baz", "value": "qux"},
+				{"op": "test", "path": "/foo/1", "value": 2}
+			]`,
+			want: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:  "A.9 testing a value, error",
+			doc:   `{"baz": "qux"}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "A.10 adding a nested member object",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:  `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:  "A.11 ignoring unrecognized elements",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			want:  `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:  "A.12 adding to a nonexistent target",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "A.14 ~ escape ordering",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:  `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:  "A.15 comparing strings and numbers",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "A.16 adding an array value",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		{
+			name:  "~1 addresses a member with a slash",
+			doc:   `{"a/b": 1}`,
+			patch: `[{"op": "replace", "path": "/a~1b", "value": 2}]`,
+			want:  `{"a/b": 2}`,
+		},
+		{
+			name:  "~0 addresses a member with a tilde",
+			doc:   `{"m~n": 1}`,
+			patch: `[{"op": "remove", "path": "/m~0n"}]`,
+			want:  `{}`,
+		},
+		{
+			name:  "- is only valid for add",
+			doc:   `{"foo": [1]}`,
+			patch: `[{"op": "remove", "path": "/foo/-"}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "add at the array length appends",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "add", "path": "/foo/2", "value": 3}]`,
+			want:  `{"foo": [1, 2, 3]}`,
+		},
+		{
+			name:  "add past the array length",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "add", "path": "/foo/3", "value": 3}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "leading zero index",
+			doc:   `{"foo": [1, 2]}`,
+			patch: `[{"op": "replace", "path": "/foo/01", "value": 3}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "add replaces an existing member",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "add", "path": "/foo", "value": 2}]`,
+			want:  `{"foo": 2}`,
+		},
+		{
+			name:  "replace needs an existing member",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "replace", "path": "/bar", "value": 2}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "replace the whole document",
+			doc:   `{"foo": 1}`,
+			patch: `[{"op": "replace", "path": "", "value": [1]}]`,
+			want:  `[1]`,
+		},
+		{
+			name:  "copy is deep",
+			doc:   `{"a": {"b": [1]}}`,
+			patch: `[{"op": "copy", "from": "/a", "path": "/c"}, {"op": "add", "path": "/c/b/-", "value": 2}]`,
+			want:  `{"a": {"b": [1]}, "c": {"b": [1, 2]}}`,
+		},
+		{
+			name:  "copy needs from",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "copy", "path": "/b"}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "move into its own child",
+			doc:   `{"a": {"b": {}}}`,
+			patch: `[{"op": "move", "from": "/a", "path": "/a/b/c"}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "move onto itself",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "move", "from": "/a", "path": "/a"}]`,
+			want:  `{"a": 1}`,
+		},
+		{
+			name:  "test compares numbers by value",
+			doc:   `{"a": 1.0}`,
+			patch: `[{"op": "test", "path": "/a", "value": 1e0}]`,
+			want:  `{"a": 1.0}`,
+		},
+		{
+			name:  "test compares objects whatever the member order",
+			doc:   `{"a": {"x": 1, "y": [true, null]}}`,
+			patch: `[{"op": "test", "path": "/a", "value": {"y": [true, null], "x": 1}}]`,
+			want:  `{"a": {"x": 1, "y": [true, null]}}`,
+		},
+		{
+			name:  "unknown op",
+			doc:   `{}`,
+			patch: `[{"op": "frobnicate", "path": "/a"}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "missing value",
+			doc:   `{}`,
+			patch: `[{"op": "add", "path": "/a"}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "path without a leading slash",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "remove", "path": "a"}]`,
+			err:   ErrInvalid,
+		},
+		{
+			name:  "a failing operation discards the earlier ones",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "add", "path": "/b", "value": 2}, {"op": "test", "path": "/a", "value": 2}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "patch that is not an array",
+			doc:   `{}`,
+			patch: `{"op": "add", "path": "/a", "value": 1}`,
+			err:   ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			check(t, got, err, tt.want, tt.err)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{name: "replace a member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add a member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "null removes a member", doc: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{name: "null removes only that member", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "arrays are replaced", doc: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "value replaced by an array", doc: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{name: "nested merge", doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{name: "array of objects is replaced", doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "array document", doc: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{name: "object replaced by an array", doc: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{name: "null patch", doc: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{name: "string patch", doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{name: "null inside an array is kept", doc: `{"e":null}`, patch: `{"a":1}`, want: `{"e":null,"a":1}`},
+		{name: "array replaced by an object", doc: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{name: "null in a new nested object is dropped", doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+		{name: "numbers keep their notation", doc: `{"a":1.50}`, patch: `{"b":10000000000000000001}`, want: `{"a":1.50,"b":10000000000000000001}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Merge([]byte(tt.doc), []byte(tt.patch))
+			check(t, got, err, tt.want, nil)
+		})
+	}
+}
+
+func TestMergeInvalidPatch(t *testing.T) {
+	if _, err := Merge([]byte(`{}`), []byte(`{"a":`)); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("err = %v, want %v", err, ErrInvalid)
+	}
+}
+
+func check(t *testing.T, got []byte, err error, want string, wantErr error) {
+	t.Helper()
+
+	if wantErr != nil {
+		if !errors.Is(err, wantErr) {
+			t.Fatalf("err = %v, want %v", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("result is not JSON: %s", got)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("bad test case: %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	logger "github.com/asliddinberdiev/i_tv_task/pkgs/logger/zap"
+	"github.com/pkg/errors"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(msg Message) error
+}
+
+type logSender struct {
+	log logger.Logger
+}
+
+// NewLogSender writes every message to the application log. Meant for local
+// development only, since message bodies may contain secrets.
+func NewLogSender(log logger.Logger) Sender {
+	return &logSender{log: log}
+}
+
+func (s *logSender) Send(msg Message) error {
+	s.log.Info("mail",
+		logger.String("to", msg.To),
+		logger.String("subject", msg.Subject),
+		logger.String("body", msg.Body),
+	)
+	return nil
+}
+
+type fileSender struct {
+	dir  string
+	from string
+}
+
+// NewFileSender stores every message as an .eml file inside dir.
+func NewFileSender(dir, from string) (Sender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create mail directory")
+	}
+	return &fileSender{dir: dir, from: from}, nil
+}
+
+func (s *fileSender) Send(msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%s_%s.eml", now.Format("20060102T150405.000000000"), sanitize(msg.To))
+
+	if err := os.WriteFile(filepath.Join(s.dir, name), format(s.from, msg, now), 0o600); err != nil {
+		return errors.Wrap(err, "failed to write mail file")
+	}
+	return nil
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type smtpSender struct {
+	cfg  SMTPConfig
+	auth smtp.Auth
+}
+
+// NewSMTPSender delivers messages through an SMTP server. The connection is
+// upgraded with STARTTLS when the server offers it, and PLAIN auth is used
+// when a username is set.
+func NewSMTPSender(cfg SMTPConfig) (Sender, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return &smtpSender{cfg: cfg, auth: auth}, nil
+}
+
+func (s *smtpSender) Send(msg Message) error {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	if err := smtp.SendMail(addr, s.auth, s.cfg.From, []string{msg.To}, format(s.cfg.From, msg, time.Now())); err != nil {
+		return errors.Wrap(err, "failed to send mail")
+	}
+	return nil
+}
+
+func format(from string, msg Message, date time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,312 @@
+// Package migrate applies versioned SQL migrations to Postgres. Migrations
+// are pairs of NNNNNN_name.up.sql and NNNNNN_name.down.sql files. The current
+// version is kept in a schema_migrations table laid out like golang-migrate
+// does, so databases migrated with that tool carry on where they were, and an
+// advisory lock keeps concurrent runners from racing.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"hash/crc32"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrDirty          = errors.New("database is dirty")
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+const table = "schema_migrations"
+
+// lockKey names the advisory lock held while migrating.
+var lockKey = int64(crc32.ChecksumIEEE([]byte(table)))
+
+var filePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint64
+	Name    string
+
+	up, down string
+}
+
+// MigrationStatus tells whether a migration has been applied.
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+// Status is the version of the database, 0 when no migration has been
+// applied, and the state of every known migration.
+type Status struct {
+	Version    uint64
+	Dirty      bool
+	Migrations []MigrationStatus
+}
+
+type Migrator struct {
+	db         *sql.DB
+	fsys       fs.FS
+	migrations []Migration
+}
+
+// New reads the migrations in dir of fsys. Every version needs both an up and
+// a down file.
+func New(db *sql.DB, fsys fs.FS, dir string) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read migrations")
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		match := filePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, errors.Errorf("invalid migration version in %s", entry.Name())
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, errors.Errorf("migration %d has two names, %s and %s", version, m.Name, match[2])
+		}
+
+		file := path.Join(dir, entry.Name())
+		if match[3] == "up" {
+			m.up = file
+		} else {
+			m.down = file
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, errors.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{db: db, fsys: fsys, migrations: migrations}, nil
+}
+
+// Latest returns the version of the last known migration.
+func (m *Migrator) Latest() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	var status *Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		version, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		status = &Status{Version: version, Dirty: dirty, Migrations: make([]MigrationStatus, len(m.migrations))}
+		for i, migration := range m.migrations {
+			status.Migrations[i] = MigrationStatus{Migration: migration, Applied: migration.Version <= version}
+		}
+		return nil
+	})
+	return status, err
+}
+
+// Up applies every pending migration and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down reverts the last steps applied migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		current, err := m.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		target := current - steps
+		if target < 0 {
+			target = 0
+		}
+		done, err = m.run(ctx, conn, current, target)
+		return err
+	})
+	return done, err
+}
+
+// Goto migrates up or down to version and returns the migrations run, in
+// order. Version 0 reverts every migration.
+func (m *Migrator) Goto(ctx context.Context, version uint64) ([]Migration, error) {
+	target, ok := m.position(version)
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownVersion, "version %d", version)
+	}
+
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		current, err := m.current(ctx, conn)
+		if err != nil {
+			return err
+		}
+		done, err = m.run(ctx, conn, current, target)
+		return err
+	})
+	return done, err
+}
+
+// position maps a version to how many migrations it takes to get there.
+func (m *Migrator) position(version uint64) (int, bool) {
+	if version == 0 {
+		return 0, true
+	}
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// current returns the position of the database version.
+func (m *Migrator) current(ctx context.Context, conn *sql.Conn) (int, error) {
+	version, dirty, err := readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, errors.Wrapf(ErrDirty, "a migration to version %d failed halfway; repair the schema and the %s table by hand", version, table)
+	}
+
+	current, ok := m.position(version)
+	if !ok {
+		return 0, errors.Wrapf(ErrUnknownVersion, "the database is at version %d", version)
+	}
+	return current, nil
+}
+
+// run applies or reverts migrations one at a time from position current to
+// target. Each runs in a transaction together with the version update, so a
+// failing migration leaves the database as it was.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, current, target int) ([]Migration, error) {
+	var done []Migration
+	for current != target {
+		var (
+			migration Migration
+			file      string
+			version   uint64
+		)
+		if current < target {
+			migration = m.migrations[current]
+			file, version = migration.up, migration.Version
+			current++
+		} else {
+			migration = m.migrations[current-1]
+			file = migration.down
+			current--
+			if current > 0 {
+				version = m.migrations[current-1].Version
+			}
+		}
+
+		body, err := fs.ReadFile(m.fsys, file)
+		if err != nil {
+			return done, errors.Wrapf(err, "failed to read %s", file)
+		}
+
+		err = inTx(ctx, conn, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, string(body)); err != nil {
+				return err
+			}
+			return writeVersion(ctx, tx, version)
+		})
+		if err != nil {
+			return done, errors.Wrapf(err, "migration %s failed", path.Base(file))
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// locked runs fn on a single connection holding the migration lock. Session
+// advisory locks belong to a connection, hence the dedicated one.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get a connection")
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return errors.Wrap(err, "failed to take the migration lock")
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+table+` (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`); err != nil {
+		return errors.Wrapf(err, "failed to create %s", table)
+	}
+
+	return fn(conn)
+}
+
+func readVersion(ctx context.Context, conn *sql.Conn) (uint64, bool, error) {
+	var (
+		version uint64
+		dirty   bool
+	)
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM `+table+` LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "failed to read %s", table)
+	}
+	return version, dirty, nil
+}
+
+// writeVersion keeps the single row of the version table; version 0 leaves it
+// empty.
+func writeVersion(ctx context.Context, tx *sql.Tx, version uint64) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+table); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO `+table+` (version, dirty) VALUES ($1, FALSE)`, version)
+	return err
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
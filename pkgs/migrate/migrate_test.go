@@ -0,0 +1,337 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pkg/errors"
+)
+
+func file(body string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(body)}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  []Migration
+		err   string
+	}{
+		{
+			name: "ordered by version, not by name",
+			files: fstest.MapFS{
+				"m/10_ten.up.sql":         file(""),
+				"m/10_ten.down.sql":       file(""),
+				"m/000002_two.up.sql":     file(""),
+				"m/000002_two.down.sql":   file(""),
+				"m/1_one.down.sql":        file(""),
+				"m/1_one.up.sql":          file(""),
+				"m/README.md":             file(""),
+				"m/3_dir.up.sql/x.sql":    file(""),
+				"m/other/4_four.up.sql":   file(""),
+				"m/other/4_four.down.sql": file(""),
+			},
+			want: []Migration{
+				{Version: 1, Name: "one", up: "m/1_one.up.sql", down: "m/1_one.down.sql"},
+				{Version: 2, Name: "two", up: "m/000002_two.up.sql", down: "m/000002_two.down.sql"},
+				{Version: 10, Name: "ten", up: "m/10_ten.up.sql", down: "m/10_ten.down.sql"},
+			},
+		},
+		{
+			name:  "empty directory",
+			files: fstest.MapFS{"m/README.md": file("")},
+			want:  []Migration{},
+		},
+		{
+			name:  "missing down file",
+			files: fstest.MapFS{"m/1_one.up.sql": file("")},
+			err:   "needs both an up and a down file",
+		},
+		{
+			name:  "missing up file",
+			files: fstest.MapFS{"m/1_one.down.sql": file("")},
+			err:   "needs both an up and a down file",
+		},
+		{
+			name: "one version with two names",
+			files: fstest.MapFS{
+				"m/1_one.up.sql":   file(""),
+				"m/1_uno.down.sql": file(""),
+			},
+			err: "has two names",
+		},
+		{
+			name:  "version 0",
+			files: fstest.MapFS{"m/0_zero.up.sql": file(""), "m/0_zero.down.sql": file("")},
+			err:   "invalid migration version",
+		},
+		{
+			name:  "version out of range",
+			files: fstest.MapFS{"m/99999999999999999999_big.up.sql": file("")},
+			err:   "invalid migration version",
+		},
+		{
+			name:  "missing directory",
+			files: fstest.MapFS{},
+			err:   "failed to read migrations",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(nil, tt.files, "m")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(m.migrations) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", m.migrations, tt.want)
+			}
+			for i := range tt.want {
+				if m.migrations[i] != tt.want[i] {
+					t.Errorf("migration %d = %+v, want %+v", i, m.migrations[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPosition(t *testing.T) {
+	m, err := New(nil, fstest.MapFS{
+		"m/3_a.up.sql": file(""), "m/3_a.down.sql": file(""),
+		"m/7_b.up.sql": file(""), "m/7_b.down.sql": file(""),
+	}, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := m.Latest(); got != 7 {
+		t.Errorf("Latest = %d, want 7", got)
+	}
+
+	tests := []struct {
+		version uint64
+		want    int
+		ok      bool
+	}{
+		{version: 0, want: 0, ok: true},
+		{version: 3, want: 1, ok: true},
+		{version: 7, want: 2, ok: true},
+		{version: 5, ok: false},
+		{version: 8, ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := m.position(tt.version)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("position(%d) = %d, %v, want %d, %v", tt.version, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	empty, err := New(nil, fstest.MapFS{"m": &fstest.MapFile{Mode: os.ModeDir}}, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := empty.Latest(); got != 0 {
+		t.Errorf("Latest of no migrations = %d, want 0", got)
+	}
+}
+
+// TestLockKey pins the advisory lock key. Runners of different releases only
+// exclude each other while they agree on it.
+func TestLockKey(t *testing.T) {
+	if lockKey != 4156727022 {
+		t.Fatalf("lockKey = %d, the CRC-32 of %q changed", lockKey, table)
+	}
+}
+
+// testDB connects to the database named by MIGRATE_TEST_DSN. The tests drop
+// every table they create, so point it at a scratch database.
+func testDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv("MIGRATE_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MIGRATE_TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drop := func() {
+		if _, err := db.Exec(`DROP TABLE IF EXISTS ` + table + `, migrate_a, migrate_b`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drop()
+	t.Cleanup(func() {
+		drop()
+		db.Close()
+	})
+	return db
+}
+
+var testMigrations = fstest.MapFS{
+	"m/1_a.up.sql":   file(`CREATE TABLE migrate_a (id INT)`),
+	"m/1_a.down.sql": file(`DROP TABLE migrate_a`),
+	"m/2_b.up.sql":   file(`CREATE TABLE migrate_b (id INT); INSERT INTO migrate_b VALUES (1)`),
+	"m/2_b.down.sql": file(`DROP TABLE migrate_b`),
+}
+
+func versions(migrations []Migration) []uint64 {
+	out := make([]uint64, len(migrations))
+	for i, m := range migrations {
+		out[i] = m.Version
+	}
+	return out
+}
+
+func TestMigrate(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	m, err := New(db, testMigrations, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("Up ran %v, want [1 2]", got)
+	}
+
+	done, err = m.Up(ctx)
+	if err != nil || len(done) != 0 {
+		t.Fatalf("second Up ran %v, %v", versions(done), err)
+	}
+
+	done, err = m.Down(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("Down ran %v, want [2]", got)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != 1 || status.Dirty || !status.Migrations[0].Applied || status.Migrations[1].Applied {
+		t.Fatalf("status = %+v", status)
+	}
+
+	if _, err := m.Goto(ctx, 5); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("Goto unknown version: err = %v", err)
+	}
+
+	done, err = m.Goto(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("Goto 0 ran %v, want [1]", got)
+	}
+}
+
+func TestMigrateFailureRollsBack(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	files := fstest.MapFS{
+		"m/1_a.up.sql":   testMigrations["m/1_a.up.sql"],
+		"m/1_a.down.sql": testMigrations["m/1_a.down.sql"],
+		"m/2_b.up.sql":   file(`CREATE TABLE migrate_b (id INT); SELECT * FROM no_such_table`),
+		"m/2_b.down.sql": testMigrations["m/2_b.down.sql"],
+	}
+	m, err := New(db, files, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "2_b.up.sql") {
+		t.Fatalf("err = %v, want the failing file named", err)
+	}
+	if got := versions(done); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("Up ran %v, want [1]", got)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != 1 || status.Dirty {
+		t.Fatalf("status = %+v, want version 1 and clean", status)
+	}
+
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass('migrate_b') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("the failed migration left migrate_b behind")
+	}
+}
+
+// TestMigrateConcurrently runs several migrators at once. The advisory lock
+// must let exactly one of them apply each migration.
+func TestMigrateConcurrently(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	const runners = 5
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total []uint64
+	)
+	for i := 0; i < runners; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			m, err := New(db, testMigrations, "m")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			done, err := m.Up(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			total = append(total, versions(done)...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(total) != 2 {
+		t.Fatalf("migrations applied %v times in total, want each once", total)
+	}
+
+	var rows int
+	if err := db.QueryRow(`SELECT count(*) FROM migrate_b`).Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 1 {
+		t.Fatalf("migrate_b has %d rows, want 1", rows)
+	}
+}
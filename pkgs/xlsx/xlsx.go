@@ -0,0 +1,163 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+	rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+	workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+	// styles defines cell format 1 as a date and time, used for time.Time.
+	styles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>
+<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>
+</styleSheet>`
+
+	sheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	sheetEnd = `</sheetData></worksheet>`
+)
+
+// excelEpoch is day zero of the 1900 date system, shifted for its
+// nonexistent 1900-02-29.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Writer writes a single-sheet Excel workbook row by row. Rows go straight
+// into the zip stream, so memory use does not grow with the sheet.
+type Writer struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+	buf   strings.Builder
+}
+
+// NewWriter starts a workbook whose only sheet is named sheetName. Rows are
+// added with WriteRow and the workbook is finished by Close.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+
+	var name strings.Builder
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+
+	parts := []struct{ path, body string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/styles.xml", styles},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create %s", part.path)
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, errors.Wrapf(err, "failed to write %s", part.path)
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create sheet")
+	}
+	if _, err := io.WriteString(sheet, sheetStart); err != nil {
+		return nil, errors.Wrap(err, "failed to write sheet")
+	}
+
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Numbers and times become numeric cells, nil an
+// empty cell, and everything else an inline string.
+func (w *Writer) WriteRow(values ...interface{}) error {
+	w.rows++
+	w.buf.Reset()
+	w.buf.WriteString(`<row r="`)
+	w.buf.WriteString(strconv.Itoa(w.rows))
+	w.buf.WriteString(`">`)
+
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			w.buf.WriteString(`<c/>`)
+		case int:
+			w.number(strconv.Itoa(v))
+		case int64:
+			w.number(strconv.FormatInt(v, 10))
+		case uint:
+			w.number(strconv.FormatUint(uint64(v), 10))
+		case float64:
+			w.number(strconv.FormatFloat(v, 'g', -1, 64))
+		case time.Time:
+			days := v.UTC().Sub(excelEpoch).Hours() / 24
+			w.buf.WriteString(`<c s="1"><v>`)
+			w.buf.WriteString(strconv.FormatFloat(days, 'f', -1, 64))
+			w.buf.WriteString(`</v></c>`)
+		case string:
+			w.text(v)
+		default:
+			return errors.Errorf("unsupported cell type %T", value)
+		}
+	}
+
+	w.buf.WriteString(`</row>`)
+	_, err := io.WriteString(w.sheet, w.buf.String())
+	return err
+}
+
+func (w *Writer) number(v string) {
+	w.buf.WriteString(`<c><v>`)
+	w.buf.WriteString(v)
+	w.buf.WriteString(`</v></c>`)
+}
+
+func (w *Writer) text(v string) {
+	w.buf.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	_ = xml.EscapeText(&w.buf, []byte(v))
+	w.buf.WriteString(`</t></is></c>`)
+}
+
+// Close finishes the sheet and the zip archive. It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if _, err := io.WriteString(w.sheet, sheetEnd); err != nil {
+		return errors.Wrap(err, "failed to write sheet")
+	}
+	return w.zw.Close()
+}
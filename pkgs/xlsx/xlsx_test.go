@@ -0,0 +1,153 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+)
+
+type sheetXML struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			S    string `xml:"s,attr"`
+			T    string `xml:"t,attr"`
+			V    string `xml:"v"`
+			Text string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type workbookXML struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, `Movies & "more"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := [][]interface{}{
+		{"id", "title", "rating", "created_at", "note"},
+		{uint(1), "Alien <1979>", 8.5, time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC), nil},
+		{int64(-2), "  padded  ", 0.1, time.Date(1900, 3, 1, 0, 0, 0, 0, time.FixedZone("UZT", 5*3600)), 3},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = body
+	}
+
+	for _, name := range []string{
+		"[Content_Types].xml",
+		"_rels/.rels",
+		"xl/workbook.xml",
+		"xl/_rels/workbook.xml.rels",
+		"xl/styles.xml",
+		"xl/worksheets/sheet1.xml",
+	} {
+		body, ok := parts[name]
+		if !ok {
+			t.Fatalf("missing part %s", name)
+		}
+		var v struct{}
+		if err := xml.Unmarshal(body, &v); err != nil {
+			t.Fatalf("%s is not well-formed: %v", name, err)
+		}
+	}
+
+	var workbook workbookXML
+	if err := xml.Unmarshal(parts["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatal(err)
+	}
+	if len(workbook.Sheets) != 1 || workbook.Sheets[0].Name != `Movies & "more"` {
+		t.Fatalf("sheets = %+v", workbook.Sheets)
+	}
+
+	var sheet sheetXML
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != len(rows) {
+		t.Fatalf("got %d rows, want %d", len(sheet.Rows), len(rows))
+	}
+
+	type cell struct{ s, t, v, text string }
+	want := [][]cell{
+		{
+			{t: "inlineStr", text: "id"},
+			{t: "inlineStr", text: "title"},
+			{t: "inlineStr", text: "rating"},
+			{t: "inlineStr", text: "created_at"},
+			{t: "inlineStr", text: "note"},
+		},
+		{
+			{v: "1"},
+			{t: "inlineStr", text: "Alien <1979>"},
+			{v: "8.5"},
+			{s: "1", v: "36526.5"},
+			{},
+		},
+		{
+			{v: "-2"},
+			{t: "inlineStr", text: "  padded  "},
+			{v: "0.1"},
+			{s: "1", v: "60.791666666666664"},
+			{v: "3"},
+		},
+	}
+
+	for i, row := range sheet.Rows {
+		if row.R != i+1 {
+			t.Errorf("row %d has r=%d", i+1, row.R)
+		}
+		if len(row.Cells) != len(want[i]) {
+			t.Fatalf("row %d has %d cells, want %d", i+1, len(row.Cells), len(want[i]))
+		}
+		for j, c := range row.Cells {
+			got := cell{s: c.S, t: c.T, v: c.V, text: c.Text}
+			if got != want[i][j] {
+				t.Errorf("row %d cell %d = %+v, want %+v", i+1, j+1, got, want[i][j])
+			}
+		}
+	}
+}
+
+func TestWriteRowUnsupportedType(t *testing.T) {
+	w, err := NewWriter(io.Discard, "Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(true); err == nil {
+		t.Fatal("expected an error for a bool cell")
+	}
+}